@@ -54,7 +54,8 @@ func noticef(ctx context.Context, format string, v ...interface{}) {
 			return
 		}
 	}
-	LogObj.Output(fmt.Sprintf(format, v...), logfox.NoticeLevel)
+	// 请求中的cookie、token、手机号等脱敏后再输出
+	LogObj.Output(log.Redact(fmt.Sprintf(format, v...)), logfox.NoticeLevel)
 }
 
 //http项目的accesslog日志
@@ -75,7 +76,7 @@ func WriteHTTPLog(writer io.Writer, params handlers.LogFormatterParams) {
 			return
 		}
 	}
-	LogObj.Output(buf, logfox.NoticeLevel)
+	LogObj.Output(log.Redact(buf), logfox.NoticeLevel)
 }
 func buildCommonLogLine(req *http.Request, url url.URL, ts time.Time, status int, size int) string {
 	username := "-"
@@ -0,0 +1,14 @@
+package util
+
+import "regexp"
+
+// 邮箱、手机号的正则，validate的校验和log的脱敏共用，Pattern不带^$，可以在文本中查找
+const (
+	EmailPattern  = `(?i)[0-9a-z._\-]{2,64}@[a-z0-9\-]{1,63}(\.[a-z]{2,6})+`
+	MobilePattern = `(\+86)?1[0-9]{10}` // 考虑中国带国家区号的 +8618621557027 形式
+)
+
+var (
+	RegexpEmail  = regexp.MustCompile(`^(?:` + EmailPattern + `)$`)
+	RegexpMobile = regexp.MustCompile(`^(?:` + MobilePattern + `)$`)
+)
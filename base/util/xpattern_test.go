@@ -0,0 +1,30 @@
+package util
+
+import "testing"
+
+func TestRegexpMobile(t *testing.T) {
+	for s, want := range map[string]bool{
+		"13800138000":    true,
+		"+8613800138000": true,
+		"23800138000":    false,
+		"1380013800":     false,
+		"138001380001":   false,
+	} {
+		if got := RegexpMobile.MatchString(s); got != want {
+			t.Errorf("RegexpMobile(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestRegexpEmail(t *testing.T) {
+	for s, want := range map[string]bool{
+		"foo.bar@example.com": true,
+		"FOO@EXAMPLE.COM":     true,
+		"foo":                 false,
+		"a@example.com":       false,
+	} {
+		if got := RegexpEmail.MatchString(s); got != want {
+			t.Errorf("RegexpEmail(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
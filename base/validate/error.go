@@ -14,6 +14,11 @@ type FieldError struct {
 	Message  string
 }
 
+// Error 实现error，自定义的Validate() error可以直接返回FieldError
+func (e FieldError) Error() string {
+	return e.Message
+}
+
 func newBindError(field, ruleName, message string, args ...interface{}) *FieldError {
 	var formatedMsg string
 	if len(args) == 0 {
@@ -12,18 +12,6 @@ import (
 	"github.com/zer0131/toolbox/log"
 )
 
-var (
-	regexpEmail *regexp.Regexp
-)
-
-func init() {
-	var err error
-	regexpEmail, err = regexp.Compile("(?i)^([0-9a-z._\\-]{2,64})@([a-z0-9\\-]{1,63})(\\.[a-z]{2,6})+$")
-	if err != nil {
-		panic(err)
-	}
-}
-
 func FnRequired(val interface{}) bool {
 	if val == nil {
 		return false
@@ -200,7 +188,7 @@ func FnIsEmail(val string) bool {
 	if val == `` {
 		return false
 	}
-	return regexpEmail.MatchString(val)
+	return util.RegexpEmail.MatchString(val)
 }
 
 func FnIsIPv4(val string) bool {
@@ -299,16 +287,7 @@ func FnIsBase64(val string) bool {
 }
 
 func FnIsMobile(val string) bool {
-	if strings.HasPrefix(val, "+86") { //考虑中国带国家区号的 +8618621557027 形式
-		val = val[3:]
-	}
-	if len(val) != 11 {
-		return false
-	}
-	if val[0] != '1' {
-		return false
-	}
-	return FnIsDigit(val)
+	return util.RegexpMobile.MatchString(val)
 }
 
 func FnIsTel(val string) bool {
@@ -0,0 +1,53 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/zer0131/toolbox/log"
+)
+
+// ValidateStruct 按validate标签校验已经赋值的结构体，例如grpc的请求，
+// 字段名与BindFromValues一致，取name标签，没有时为小写的字段名
+func ValidateStruct(ctx context.Context, v interface{}) BindingResult {
+	strct := reflect.Indirect(reflect.ValueOf(v))
+	if strct.Kind() != reflect.Struct {
+		return BindingResult{OK: false, Err: errors.New("parameter must be struct or pointer of struct")}
+	}
+	strctTyp := strct.Type()
+	fieldNum := strctTyp.NumField()
+	retval := BindingResult{OK: true}
+	for i := 0; i < fieldNum; i++ {
+		fieldDef := strctTyp.Field(i)
+		// 未导出的字段取不到值
+		if fieldDef.PkgPath != `` {
+			continue
+		}
+		validateRules := fieldDef.Tag.Get(`validate`)
+		if validateRules == `` {
+			continue
+		}
+		options, err := parseExpressions(validateRules)
+		if err != nil {
+			log.Warnf(ctx, "Error when parseExpressions(%#v) for ValidateRules, error is %s, Rule is [%s]", validateRules, err.Error(), validateRules)
+			continue
+		}
+		validators := CreateValidators(ctx, options)
+
+		field := strct.Field(i)
+		if validators.Optional && field.IsZero() {
+			continue
+		}
+		name := fieldDef.Tag.Get(`name`)
+		if name == `` {
+			name = strings.ToLower(fieldDef.Name)
+		}
+		if err := validators.Validate(ctx, name, field.Interface()); err != nil {
+			retval.OK = false
+			retval.FieldErrors = append(retval.FieldErrors, *err)
+		}
+	}
+	return retval
+}
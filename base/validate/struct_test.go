@@ -0,0 +1,35 @@
+package validate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateStruct(t *testing.T) {
+	type Request struct {
+		Name  string `validate:"min_length(2),max_length(5)"`
+		Age   int32  `name:"user_age" validate:"range(0, 150)"`
+		Email string `validate:"email, optional"`
+		Data  []string
+	}
+
+	br := ValidateStruct(context.Background(), &Request{Name: "tom", Age: 20})
+	if !br.OK || len(br.FieldErrors) != 0 {
+		t.Errorf("expect ok actual %+v", br)
+	}
+
+	br = ValidateStruct(context.Background(), &Request{Name: "t", Age: 200, Email: "foo"})
+	if br.OK || len(br.FieldErrors) != 3 {
+		t.Fatalf("expect 3 field errors actual %+v", br)
+	}
+	expectFields := []string{"name", "user_age", "email"}
+	for i, fe := range br.FieldErrors {
+		if fe.Field != expectFields[i] {
+			t.Errorf("Index %d expect field %s actual %s", i, expectFields[i], fe.Field)
+		}
+	}
+
+	if br = ValidateStruct(context.Background(), "foo"); br.OK || br.Err == nil {
+		t.Errorf("expect err for non struct")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"github.com/pkg/errors"
+	"io"
 	"io/ioutil"
+	"os"
 )
 
 func GzipMarshal(raw []byte) ([]byte, error) {
@@ -36,3 +38,38 @@ func GzipUnmarshal(raw []byte) ([]byte, error) {
 	}
 	return rr, nil
 }
+
+// GzipFile 流式压缩文件，与GzipMarshal使用相同的压缩级别，适合不能整个读入内存的大文件，
+// 成功后删除src
+func GzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	gz, err := gzip.NewWriterLevel(out, flate.DefaultCompression)
+	if err != nil {
+		_ = out.Close()
+		return errors.Wrap(err, "")
+	}
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return errors.Wrap(err, "")
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return errors.Wrap(err, "")
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return errors.Wrap(err, "")
+	}
+	return errors.Wrap(os.Remove(src), "")
+}
@@ -1,6 +1,11 @@
 package common
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func Test_Gzip(t *testing.T) {
 
@@ -24,3 +29,27 @@ func Test_Gzip(t *testing.T) {
 	}
 
 }
+
+func Test_GzipFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app.log")
+	raw := []byte("line1\nline2\n")
+	if err := ioutil.WriteFile(src, raw, 0644); err != nil {
+		t.Fatalf("write err=%s", err)
+	}
+
+	if err := GzipFile(src, src+".gz"); err != nil {
+		t.Fatalf("gzip err=%s", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expect src removed")
+	}
+	b, err := ioutil.ReadFile(src + ".gz")
+	if err != nil {
+		t.Fatalf("read err=%s", err)
+	}
+	r, err := GzipUnmarshal(b)
+	if err != nil || string(r) != string(raw) {
+		t.Errorf("unexpected content %s err=%v", r, err)
+	}
+}
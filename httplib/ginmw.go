@@ -3,6 +3,7 @@ package httplib
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +25,21 @@ func CheckLogIdMwForGin() gin.HandlerFunc {
 	}
 }
 
+// TraceMwForGin 同TraceMw，需要放在CheckLogIdMwForGin之前
+func TraceMwForGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := startServerSpan(c.Request)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		span.SetAttribute("http.status_code", strconv.Itoa(c.Writer.Status()))
+		if len(c.Errors) > 0 {
+			span.SetError(c.Errors.Last())
+		}
+	}
+}
+
 // CheckIpForGin 检查Ip
 func CheckIpForGin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -77,3 +93,9 @@ func LoggerToFile() gin.HandlerFunc {
 		)
 	}
 }
+
+// LogLevelForGin 查看和修改日志level，参考log.LevelHandler，
+// 例如r.Any("/debug/log/level", LogLevelForGin())
+func LogLevelForGin() gin.HandlerFunc {
+	return gin.WrapH(log.LevelHandler())
+}
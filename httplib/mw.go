@@ -1,11 +1,16 @@
 package httplib
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/zer0131/toolbox/ip"
 	"github.com/zer0131/toolbox/log"
+	"github.com/zer0131/toolbox/trace"
 )
 
 // 补全log-id，如果不存在
@@ -55,3 +60,73 @@ func AddStat(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// TraceMw 从traceparent恢复上游的trace，为每个请求创建server span，放在r.Context()中，
+// 没有log-id时使用trace id补全，需要放在CheckLogIdMw之前
+func TraceMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := startServerSpan(r)
+		defer span.End()
+
+		sw := NewStatusWriter(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		span.SetAttribute("http.status_code", strconv.Itoa(sw.Status()))
+	})
+}
+
+func startServerSpan(r *http.Request) (context.Context, *trace.Span) {
+	ctx := r.Context()
+	if sc, ok := trace.ParseTraceParent(r.Header.Get(trace.TraceParentKey)); ok {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := trace.Start(ctx, r.Method+" "+r.URL.Path, trace.KindServer)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.RequestURI())
+	span.SetAttribute("net.peer.addr", r.RemoteAddr)
+
+	if r.Header.Get(log.LogIDKey) == "" {
+		r.Header.Set(log.LogIDKey, span.SpanContext().TraceID.String())
+	}
+	return ctx, span
+}
+
+// StatusWriter 记录handler写入的状态码，供中间件打点和trace使用，
+// 底层ResponseWriter支持时转发Flush和Hijack，不影响sse、websocket
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status 没有调用WriteHeader时为200
+func (w *StatusWriter) Status() int {
+	return w.status
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 底层ResponseWriter不支持时返回错误
+func (w *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not implement http.Hijacker", w.ResponseWriter)
+	}
+	return h.Hijack()
+}
+
+// Unwrap 供http.ResponseController获取底层的ResponseWriter
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,29 @@
+package httplib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = NewStatusWriter(rec)
+
+	w.WriteHeader(http.StatusAccepted)
+	if sw := w.(*StatusWriter); sw.Status() != http.StatusAccepted {
+		t.Errorf("expect status %d actual %d", http.StatusAccepted, sw.Status())
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("expect http.Flusher")
+	}
+	flusher.Flush()
+	if !rec.Flushed {
+		t.Error("expect flush forwarded")
+	}
+	// ResponseRecorder不支持Hijack
+	if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+		t.Error("expect hijack err")
+	}
+}
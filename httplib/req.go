@@ -11,12 +11,14 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/zer0131/toolbox"
 	"github.com/zer0131/toolbox/log"
 	"github.com/zer0131/toolbox/stat"
+	"github.com/zer0131/toolbox/trace"
 )
 
 const (
@@ -58,7 +60,6 @@ func (c *HttpClient) Get(ctx context.Context, api string) ([]byte, error) {
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -72,16 +73,18 @@ func (c *HttpClient) Get(ctx context.Context, api string) ([]byte, error) {
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodGet, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 		reqErr = err
 		if err != nil {
 			continue
 		}
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -91,7 +94,10 @@ func (c *HttpClient) Get(ctx context.Context, api string) ([]byte, error) {
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -116,7 +122,6 @@ func (c *HttpClient) Post(ctx context.Context, api string, data []byte) ([]byte,
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -130,11 +135,12 @@ func (c *HttpClient) Post(ctx context.Context, api string, data []byte) ([]byte,
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	// bug: 每次retry都要用新的req，否则出现：Post http://10.188.40.13:8988/multi: http: ContentLength=249 with Body length 0
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(data))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewBuffer(data))
 		reqErr = err
 		if err != nil {
 			continue
@@ -142,6 +148,7 @@ func (c *HttpClient) Post(ctx context.Context, api string, data []byte) ([]byte,
 		req.Header.Add("Content-Type", "application/json")
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -151,7 +158,10 @@ func (c *HttpClient) Post(ctx context.Context, api string, data []byte) ([]byte,
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -177,11 +187,12 @@ func (c *HttpClient) RawPost(ctx context.Context, rawUrl string, data []byte) ([
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, rawUrl)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	// bug: 每次retry都要用新的req，否则出现：Post http://10.188.40.13:8988/multi: http: ContentLength=249 with Body length 0
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, rawUrl, bytes.NewBuffer(data))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawUrl, bytes.NewBuffer(data))
 		reqErr = err
 		if err != nil {
 			continue
@@ -189,6 +200,7 @@ func (c *HttpClient) RawPost(ctx context.Context, rawUrl string, data []byte) ([
 		req.Header.Add("Content-Type", "application/json")
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -198,7 +210,10 @@ func (c *HttpClient) RawPost(ctx context.Context, rawUrl string, data []byte) ([
 
 		break
 	}
+	reqStat(rawUrl, resp, reqErr, startReqTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, rawUrl, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -223,16 +238,20 @@ func (c *HttpClient) RawPostForm(ctx context.Context, rawUrl string, values url.
 		reqErr error
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, rawUrl)
+	logId := reqLogId(ctx)
+	startReqTime := time.Now()
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, rawUrl, strings.NewReader(values.Encode()))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawUrl, strings.NewReader(values.Encode()))
 		reqErr = err
 		if err != nil {
-			return nil, err
+			// 请求构造失败重试也没有意义，统一在循环外统计和结束span
+			break
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -242,7 +261,10 @@ func (c *HttpClient) RawPostForm(ctx context.Context, rawUrl string, values url.
 
 		break
 	}
+	reqStat(rawUrl, resp, reqErr, startReqTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, rawUrl, reqErr)
 		return nil, reqErr
 	}
 	defer func() {
@@ -265,7 +287,6 @@ func (c *HttpClient) PostForm(ctx context.Context, api string, values url.Values
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -279,17 +300,20 @@ func (c *HttpClient) PostForm(ctx context.Context, api string, values url.Values
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, urlStr, strings.NewReader(values.Encode()))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, strings.NewReader(values.Encode()))
 		reqErr = err
 		if err != nil {
-			return nil, err
+			// 请求构造失败重试也没有意义，统一在循环外统计和结束span
+			break
 		}
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -299,7 +323,10 @@ func (c *HttpClient) PostForm(ctx context.Context, api string, values url.Values
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -324,7 +351,6 @@ func (c *HttpClient) PostFile(ctx context.Context, api string, header map[string
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -338,11 +364,12 @@ func (c *HttpClient) PostFile(ctx context.Context, api string, header map[string
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	// bug: 每次retry都要用新的req，否则出现：Post http://10.188.40.13:8988/multi: http: ContentLength=249 with Body length 0
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, urlStr, data)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, data)
 		reqErr = err
 		if err != nil {
 			continue
@@ -351,6 +378,7 @@ func (c *HttpClient) PostFile(ctx context.Context, api string, header map[string
 			req.Header.Set(k, v)
 		}
 		req.Header.Add("log-id", logId)
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -360,7 +388,10 @@ func (c *HttpClient) PostFile(ctx context.Context, api string, header map[string
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -385,7 +416,6 @@ func (c *HttpClient) PostWithHeader(ctx context.Context, api string, header map[
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -399,10 +429,11 @@ func (c *HttpClient) PostWithHeader(ctx context.Context, api string, header map[
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodPost, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodPost, urlStr, bytes.NewBuffer(data))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewBuffer(data))
 		reqErr = err
 		if err != nil {
 			continue
@@ -412,6 +443,7 @@ func (c *HttpClient) PostWithHeader(ctx context.Context, api string, header map[
 		}
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -421,7 +453,10 @@ func (c *HttpClient) PostWithHeader(ctx context.Context, api string, header map[
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -446,7 +481,6 @@ func (c *HttpClient) Delete(ctx context.Context, api string) ([]byte, error) {
 	}
 
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(fmt.Sprintf("%s.%s", stat.Http, stat.GetRawPath(api))), startTime)
 
 	ap, err := Addr(c.addr).Parse()
 	if err != nil {
@@ -460,16 +494,18 @@ func (c *HttpClient) Delete(ctx context.Context, api string) ([]byte, error) {
 		costTime float32
 	)
 
-	logId, _ := log.LogIdFromContext(ctx)
+	ctx, span := startReqSpan(ctx, http.MethodDelete, urlStr)
+	logId := reqLogId(ctx)
 	startReqTime := time.Now()
 	for i := 0; i < c.retry; i++ {
-		req, err := http.NewRequest(http.MethodDelete, urlStr, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, urlStr, nil)
 		reqErr = err
 		if err != nil {
 			continue
 		}
 		req.Header.Add("log-id", logId)
 		req.Header.Add("remote-addr", toolbox.LocalIP())
+		setTraceParent(req.Header, span)
 
 		resp, err = c.hc.Do(req)
 		reqErr = err
@@ -479,7 +515,10 @@ func (c *HttpClient) Delete(ctx context.Context, api string) ([]byte, error) {
 
 		break
 	}
+	reqStat(urlStr, resp, reqErr, startTime)
+	endReqSpan(span, resp, reqErr)
 	if reqErr != nil {
+		printReqErr(ctx, urlStr, reqErr)
 		return nil, reqErr
 	}
 	costTime = float32(time.Now().UnixNano()-startReqTime.UnixNano()) / 1e9
@@ -498,6 +537,72 @@ func (c *HttpClient) Delete(ctx context.Context, api string) ([]byte, error) {
 	return r, nil
 }
 
+// 一次请求（包含重试）只上报一次，结果码为最后一次的http状态码
+func reqStat(urlStr string, resp *http.Response, err error, startTime time.Time) {
+	var (
+		host       string
+		statusCode int
+	)
+	if u, perr := url.Parse(urlStr); perr == nil {
+		host = u.Host
+	}
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	stat.ClientStatV2(stat.Labels{
+		Client: stat.Http,
+		Op:     stat.GetRawPath(urlStr),
+		Addr:   host,
+		Code:   stat.HttpCode(statusCode, err),
+	}, startTime)
+}
+
+// startReqSpan 一次请求（包含重试）对应一个client span，traceparent通过header传给下游，
+// 与middleware一样只在ctx中已有trace时创建，否则返回nil span
+func startReqSpan(ctx context.Context, method, urlStr string) (context.Context, *trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	newCtx, span := trace.Start(ctx, method+" "+stat.GetRawPath(urlStr), trace.KindClient)
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.url", urlStr)
+	return newCtx, span
+}
+
+func setTraceParent(header http.Header, span *trace.Span) {
+	if span != nil {
+		header.Set(trace.TraceParentKey, span.SpanContext().TraceParent())
+	}
+}
+
+func endReqSpan(span *trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+	}
+	span.SetError(err)
+	span.End()
+}
+
+// reqLogId 上游没有传log-id时使用trace id，方便按同一个id串联日志和trace，都没有时为空
+func reqLogId(ctx context.Context) string {
+	if logId, ok := log.LogIdFromContext(ctx); ok {
+		return logId
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID.String()
+	}
+	return ""
+}
+
+// printReqErr 没有拿到响应时记录日志，超时单独区分
+func printReqErr(ctx context.Context, urlStr string, err error) {
+	if stat.IsTimeout(err) {
+		log.Warnf(ctx, "timeout err=%s url=%s", err, urlStr)
+		return
+	}
+	log.Warnf(ctx, "err=%s url=%s", err, urlStr)
+}
+
 func printReqLog(ctx context.Context, response *http.Response, err error, urlStr string, cost float32) {
 	if response == nil {
 		log.Infof(ctx, "err=%s url=%s", err, urlStr)
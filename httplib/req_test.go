@@ -1,6 +1,16 @@
 package httplib
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zer0131/toolbox/stat"
+	"github.com/zer0131/toolbox/trace"
+)
 
 func Test_Parse(t *testing.T) {
 	var testList Addr
@@ -19,3 +29,95 @@ func Test_Parse(t *testing.T) {
 		}
 	}
 }
+
+type statRecorder struct {
+	labels []stat.Labels
+}
+
+func (r *statRecorder) Report(labels stat.Labels, cost time.Duration) {
+	r.labels = append(r.labels, labels)
+}
+
+func Test_ReqStat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	r := &statRecorder{}
+	stat.RegisterReporter(r)
+	defer stat.ResetReporters()
+
+	c, _ := InitHttpClient(HttpWithAddr(srv.URL))
+	if _, err := c.Get(context.Background(), "/foo?a=b"); err == nil {
+		t.Error("expect err for 502")
+	}
+
+	if len(r.labels) != 1 {
+		t.Fatalf("expect 1 stat actual %d", len(r.labels))
+	}
+	l := r.labels[0]
+	if l.Client != stat.Http || l.Op != "/foo" || l.Addr != strings.TrimPrefix(srv.URL, "http://") || l.Code != "502" {
+		t.Errorf("unexpected labels %+v", l)
+	}
+}
+
+func Test_Trace(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	trace.RegisterExporter(exporter)
+	defer trace.ResetExporters()
+
+	var logId string
+	srv := httptest.NewServer(TraceMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logId = r.Header.Get("log-id")
+	})))
+	defer srv.Close()
+
+	c, _ := InitHttpClient(HttpWithAddr(srv.URL))
+	if _, err := c.Get(context.Background(), "/foo"); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	// 没有trace时不创建client span
+	if spans := exporter.Spans(); len(spans) != 1 || spans[0].Kind != trace.KindServer {
+		t.Fatalf("expect only server span actual %+v", spans)
+	}
+	exporter.Reset()
+
+	ctx, parent := trace.Start(context.Background(), "parent", trace.KindInternal)
+	defer parent.End()
+	if _, err := c.Get(ctx, "/foo"); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans actual %d", len(spans))
+	}
+	server, client := spans[0], spans[1]
+	if server.Kind != trace.KindServer || client.Kind != trace.KindClient {
+		t.Fatalf("unexpected kind %s %s", server.Kind, client.Kind)
+	}
+	if server.SpanContext.TraceID != client.SpanContext.TraceID || server.ParentSpanID != client.SpanContext.SpanID {
+		t.Errorf("expect server span child of client span")
+	}
+	if logId != client.SpanContext.TraceID.String() {
+		t.Errorf("expect trace id as log-id actual %s", logId)
+	}
+	if server.Attributes["http.status_code"] != "200" || client.Attributes["http.status_code"] != "200" {
+		t.Errorf("unexpected status %s %s", server.Attributes["http.status_code"], client.Attributes["http.status_code"])
+	}
+}
+
+func Test_RawPostFormBadUrl(t *testing.T) {
+	r := &statRecorder{}
+	stat.RegisterReporter(r)
+	defer stat.ResetReporters()
+
+	c, _ := InitHttpClient()
+	if _, err := c.RawPostForm(context.Background(), "://bad", nil); err == nil {
+		t.Fatal("expect err for bad url")
+	}
+	if len(r.labels) != 1 {
+		t.Errorf("expect 1 stat actual %d", len(r.labels))
+	}
+}
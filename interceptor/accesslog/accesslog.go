@@ -0,0 +1,291 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/zer0131/logfox"
+	"github.com/zer0131/toolbox/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const redactedValue = "***"
+
+// 创建日志文件失败后，间隔这么久再重试
+const initRetryInterval = 10 * time.Second
+
+type options struct {
+	path         string
+	name         string
+	writer       io.Writer
+	payloadSize  int
+	redactFields map[string]struct{}
+}
+
+type OptionsFunc func(*options)
+
+// WithPath 日志目录，默认./log
+func WithPath(v string) OptionsFunc {
+	return func(o *options) {
+		o.path = v
+	}
+}
+
+// WithName 日志文件名，默认grpc_access，按小时切分
+func WithName(v string) OptionsFunc {
+	return func(o *options) {
+		o.name = v
+	}
+}
+
+// WithWriter 不写文件，直接写到w，每次调用一行
+func WithWriter(w io.Writer) OptionsFunc {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// WithPayload 记录请求与响应内容，超过n字节截断，默认0不记录
+func WithPayload(n int) OptionsFunc {
+	return func(o *options) {
+		o.payloadSize = n
+	}
+}
+
+// WithRedactFields 记录内容时这些字段的值替换为***，不区分大小写，对嵌套字段同样生效
+func WithRedactFields(fields ...string) OptionsFunc {
+	return func(o *options) {
+		for _, f := range fields {
+			o.redactFields[strings.ToLower(f)] = struct{}{}
+		}
+	}
+}
+
+type accessLogger struct {
+	opts options
+	out  *lineWriter
+}
+
+// lineWriter 串行写入一行，写文件时第一次写入才创建文件
+type lineWriter struct {
+	mutex   sync.Mutex
+	writer  io.Writer
+	path    string
+	name    string
+	retryAt time.Time
+}
+
+var (
+	fileWritersMutex sync.Mutex
+	// fileWriters unary和stream拦截器写同一个文件时共用一个lineWriter，key为path/name
+	fileWriters = make(map[string]*lineWriter)
+)
+
+func fileWriter(path, name string) *lineWriter {
+	key := filepath.Join(path, name)
+	fileWritersMutex.Lock()
+	defer fileWritersMutex.Unlock()
+	w, ok := fileWriters[key]
+	if !ok {
+		w = &lineWriter{path: path, name: name}
+		fileWriters[key] = w
+	}
+	return w
+}
+
+func newAccessLogger(opt ...OptionsFunc) *accessLogger {
+	opts := options{
+		path:         "./log",
+		name:         "grpc_access",
+		redactFields: make(map[string]struct{}),
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	l := &accessLogger{opts: opts}
+	if opts.writer != nil {
+		l.out = &lineWriter{writer: opts.writer}
+	} else {
+		l.out = fileWriter(opts.path, opts.name)
+	}
+	return l
+}
+
+// UnaryServerInterceptor 每次调用写一行访问日志：
+// log_id=[] method=[] peer=[] code=[] cost=[] request_size=[] response_size=[] request=[] response=[]
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	l := newAccessLogger(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+
+		line := l.line(ctx, info.FullMethod, err, startTime, messageSize(req), messageSize(resp))
+		if l.opts.payloadSize > 0 {
+			line += fmt.Sprintf(" request=[%s] response=[%s]", l.payload(req), l.payload(resp))
+		}
+		l.output(ctx, line)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 同UnaryServerInterceptor，大小为所有消息之和，不记录内容
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	l := newAccessLogger(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = stream.Context()
+		ss := &sizeStream{WrappedServerStream: wrapped}
+		err := handler(srv, ss)
+
+		ctx := stream.Context()
+		l.output(ctx, l.line(ctx, info.FullMethod, err, startTime, ss.recvSize, ss.sentSize))
+		return err
+	}
+}
+
+func (l *accessLogger) line(ctx context.Context, method string, err error, startTime time.Time, reqSize, respSize int) string {
+	var peerAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		peerAddr = p.Addr.String()
+	}
+	return fmt.Sprintf("log_id=[%s] method=[%s] peer=[%s] code=[%s] cost=[%dms] request_size=[%d] response_size=[%d]",
+		logIdFromContext(ctx), method, peerAddr, status.Code(err), time.Since(startTime).Nanoseconds()/(1000*1000), reqSize, respSize)
+}
+
+func (l *accessLogger) output(ctx context.Context, line string) {
+	l.out.writeLine(ctx, line)
+}
+
+func (w *lineWriter) writeLine(ctx context.Context, line string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.writer == nil && !w.init(ctx) {
+		return
+	}
+	if _, err := io.WriteString(w.writer, line+"\n"); err != nil {
+		log.Errorf(ctx, "write grpc access log err=%s", err.Error())
+	}
+}
+
+// init 第一次写入时才创建日志文件，失败时间隔initRetryInterval后重试，需要持有锁
+func (w *lineWriter) init(ctx context.Context) bool {
+	now := time.Now()
+	if now.Before(w.retryAt) {
+		return false
+	}
+	logObj, err := logfox.NewLogger(w.path, w.name,
+		logfox.DEFAULT_FILEWRITER_MAX_EXPIRE_DAY,
+		logfox.DEFAULT_FILEWRITER_FILE_SUFFIX_TIME_STRING)
+	if err != nil {
+		log.Errorf(ctx, "create grpc access log err=%s", err.Error())
+		w.retryAt = now.Add(initRetryInterval)
+		return false
+	}
+	w.writer = &logfoxWriter{logObj}
+	return true
+}
+
+// payload 转为json后脱敏并截断
+func (l *accessLogger) payload(msg interface{}) string {
+	if msg == nil {
+		return ""
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+	if len(l.opts.redactFields) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err == nil {
+			if rb, err := json.Marshal(l.redact(v)); err == nil {
+				b = rb
+			}
+		}
+	}
+	if n := l.opts.payloadSize; len(b) > n {
+		// 不能从多字节字符的中间截断
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		b = b[:n]
+	}
+	return string(b)
+}
+
+func (l *accessLogger) redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if _, ok := l.opts.redactFields[strings.ToLower(k)]; ok {
+				val[k] = redactedValue
+			} else {
+				val[k] = l.redact(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = l.redact(item)
+		}
+	}
+	return v
+}
+
+// header拦截器会把log-id放到ctx value中，没有经过header拦截器时从metadata中取
+func logIdFromContext(ctx context.Context) string {
+	if logId, ok := ctx.Value(log.LogIDKey).(string); ok && logId != "" {
+		return logId
+	}
+	logId, _ := log.LogIdFromContext(ctx)
+	return logId
+}
+
+// messageSize 按proto编码后的大小计算，不是proto消息时为0
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+type sizeStream struct {
+	*grpc_middleware.WrappedServerStream
+	recvSize int
+	sentSize int
+}
+
+func (s *sizeStream) SendMsg(m interface{}) error {
+	err := s.WrappedServerStream.SendMsg(m)
+	if err == nil {
+		s.sentSize += messageSize(m)
+	}
+	return err
+}
+
+func (s *sizeStream) RecvMsg(m interface{}) error {
+	err := s.WrappedServerStream.RecvMsg(m)
+	if err == nil {
+		s.recvSize += messageSize(m)
+	}
+	return err
+}
+
+type logfoxWriter struct {
+	logObj *logfox.Logger
+}
+
+func (w *logfoxWriter) Write(p []byte) (int, error) {
+	w.logObj.Output(strings.TrimSuffix(string(p), "\n"), logfox.NoticeLevel)
+	return len(p), nil
+}
@@ -0,0 +1,115 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zer0131/toolbox/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := UnaryServerInterceptor(WithWriter(&buf), WithPayload(64), WithRedactFields("Service"))
+
+	ctx := context.WithValue(context.Background(), log.LogIDKey, "123")
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 8080}})
+	req := &grpc_health_v1.HealthCheckRequest{Service: "secret"}
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	_, _ = interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+
+	line := buf.String()
+	for _, expect := range []string{
+		"log_id=[123]",
+		"method=[/grpc.health.v1.Health/Check]",
+		"peer=[10.0.0.1:8080]",
+		"code=[NotFound]",
+		"request_size=[8]",
+		`request=[{"service":"***"}]`,
+	} {
+		if !strings.Contains(line, expect) {
+			t.Errorf("expect %s in %s", expect, line)
+		}
+	}
+	if strings.Contains(line, "secret") {
+		t.Errorf("field not redacted: %s", line)
+	}
+}
+
+func TestPayloadTruncate(t *testing.T) {
+	l := newAccessLogger(WithPayload(5))
+	if v := l.payload(&grpc_health_v1.HealthCheckRequest{Service: "helloworld"}); v != `{"ser` {
+		t.Errorf("expect truncated payload actual %s", v)
+	}
+
+	// 截断位置在多字节字符中间时整个字符都不输出
+	l = newAccessLogger(WithPayload(14))
+	if v := l.payload(&grpc_health_v1.HealthCheckRequest{Service: "中文"}); v != `{"service":"` || !utf8.ValidString(v) {
+		t.Errorf("expect truncated at rune boundary actual %s", v)
+	}
+}
+
+func TestMessageSize(t *testing.T) {
+	if n := messageSize(&grpc_health_v1.HealthCheckRequest{Service: "secret"}); n != 8 {
+		t.Errorf("expect 8 actual %d", n)
+	}
+	if n := messageSize((*grpc_health_v1.HealthCheckResponse)(nil)); n != 0 {
+		t.Errorf("expect 0 for nil message actual %d", n)
+	}
+	if n := messageSize("not proto"); n != 0 {
+		t.Errorf("expect 0 for non proto actual %d", n)
+	}
+}
+
+func TestOutputRetryInit(t *testing.T) {
+	dir := t.TempDir()
+	// 目录的位置是一个文件，创建日志文件失败
+	path := filepath.Join(dir, "log")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("write file err: %s", err)
+	}
+	l := newAccessLogger(WithPath(path))
+	l.output(context.Background(), "first")
+	if l.out.writer != nil {
+		t.Fatalf("expect init failed")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove err: %s", err)
+	}
+	l.output(context.Background(), "second")
+	if l.out.writer != nil {
+		t.Fatalf("expect no retry before initRetryInterval")
+	}
+	l.out.retryAt = time.Time{}
+	l.output(context.Background(), "third")
+	if l.out.writer == nil {
+		t.Fatalf("expect init retried")
+	}
+}
+
+func TestShareFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	unary := newAccessLogger(WithPath(dir))
+	stream := newAccessLogger(WithPath(dir))
+	if unary.out != stream.out {
+		t.Errorf("expect same writer for the same file")
+	}
+	if other := newAccessLogger(WithPath(dir), WithName("other")); other.out == unary.out {
+		t.Errorf("expect different writer for different file")
+	}
+}
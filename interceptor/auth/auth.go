@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/zer0131/toolbox/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 签名相关的metadata
+const (
+	AppIdKey     = "app-id"
+	TimestampKey = "auth-timestamp"
+	SignatureKey = "auth-signature"
+)
+
+const defaultMaxSkew = 5 * time.Minute
+
+type appIdCtxKey struct{}
+
+// Sign 签名为hex(HMAC-SHA256(secret, method + "\n" + timestamp))，timestamp为unix秒
+func Sign(secret, method, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AppIdFromContext 服务端校验通过后调用方的app-id
+func AppIdFromContext(ctx context.Context) (string, bool) {
+	appId, ok := ctx.Value(appIdCtxKey{}).(string)
+	return appId, ok
+}
+
+// UnaryClientInterceptor 客户端在outgoing metadata中写入app-id、时间戳和签名
+func UnaryClientInterceptor(appId, secret string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(signContext(ctx, appId, secret, method), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientInterceptor(appId, secret string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(signContext(ctx, appId, secret, method), desc, cc, method, opts...)
+	}
+}
+
+func signContext(ctx context.Context, appId, secret, method string) context.Context {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return metadata.AppendToOutgoingContext(ctx,
+		AppIdKey, appId,
+		TimestampKey, timestamp,
+		SignatureKey, Sign(secret, method, timestamp),
+	)
+}
+
+type options struct {
+	secrets     map[string]string
+	maxSkew     time.Duration
+	allowList   map[string]map[string]struct{}
+	skipMethods map[string]struct{}
+}
+
+type OptionsFunc func(*options)
+
+// WithSecret 添加一个调用方及其密钥
+func WithSecret(appId, secret string) OptionsFunc {
+	return func(o *options) {
+		o.secrets[appId] = secret
+	}
+}
+
+// WithMaxSkew 允许的时间戳偏差，默认5分钟
+func WithMaxSkew(d time.Duration) OptionsFunc {
+	return func(o *options) {
+		o.maxSkew = d
+	}
+}
+
+// WithAllow 方法只允许这些调用方访问，没有配置的方法所有通过校验的调用方都可以访问
+func WithAllow(method string, appIds ...string) OptionsFunc {
+	return func(o *options) {
+		allow, ok := o.allowList[method]
+		if !ok {
+			allow = make(map[string]struct{})
+			o.allowList[method] = allow
+		}
+		for _, appId := range appIds {
+			allow[appId] = struct{}{}
+		}
+	}
+}
+
+// WithSkipMethods 不校验的方法，例如健康检查
+func WithSkipMethods(methods ...string) OptionsFunc {
+	return func(o *options) {
+		for _, m := range methods {
+			o.skipMethods[m] = struct{}{}
+		}
+	}
+}
+
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{
+		secrets:     make(map[string]string),
+		maxSkew:     defaultMaxSkew,
+		allowList:   make(map[string]map[string]struct{}),
+		skipMethods: make(map[string]struct{}),
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
+
+// UnaryServerInterceptor 校验签名，通过后可以使用AppIdFromContext获取调用方
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, err := opts.verify(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := opts.verify(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
+
+func (o options) verify(ctx context.Context, method string) (context.Context, error) {
+	if _, ok := o.skipMethods[method]; ok {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	appId := firstValue(md, AppIdKey)
+	timestamp := firstValue(md, TimestampKey)
+	signature := firstValue(md, SignatureKey)
+	if appId == "" || timestamp == "" || signature == "" {
+		log.Warnf(ctx, "auth missing metadata method[%s] app-id[%s]", method, appId)
+		return nil, status.Error(codes.Unauthenticated, "missing auth metadata")
+	}
+
+	secret, ok := o.secrets[appId]
+	if !ok {
+		log.Warnf(ctx, "auth unknown app-id[%s] method[%s]", appId, method)
+		return nil, status.Error(codes.Unauthenticated, "unknown app-id")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid timestamp")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > o.maxSkew || skew < -o.maxSkew {
+		log.Warnf(ctx, "auth stale timestamp app-id[%s] method[%s] timestamp[%s]", appId, method, timestamp)
+		return nil, status.Error(codes.Unauthenticated, "stale timestamp")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, method, timestamp))) {
+		log.Warnf(ctx, "auth invalid signature app-id[%s] method[%s]", appId, method)
+		return nil, status.Error(codes.Unauthenticated, "invalid signature")
+	}
+
+	if allow, ok := o.allowList[method]; ok {
+		if _, ok := allow[appId]; !ok {
+			log.Warnf(ctx, "auth app-id[%s] not allowed method[%s]", appId, method)
+			return nil, status.Error(codes.PermissionDenied, "app-id not allowed")
+		}
+	}
+
+	return context.WithValue(ctx, appIdCtxKey{}, appId), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testMethod = "/helloworld.Greeter/SayHello"
+
+// 把客户端写入的outgoing metadata转为服务端的incoming metadata
+func signedIncomingContext(appId, secret, method string) context.Context {
+	md, _ := metadata.FromOutgoingContext(signContext(context.Background(), appId, secret, method))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func callServer(interceptor grpc.UnaryServerInterceptor, ctx context.Context) (string, error) {
+	var appId string
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: testMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		appId, _ = AppIdFromContext(ctx)
+		return nil, nil
+	})
+	return appId, err
+}
+
+func TestVerify(t *testing.T) {
+	interceptor := UnaryServerInterceptor(WithSecret("app1", "secret1"))
+
+	appId, err := callServer(interceptor, signedIncomingContext("app1", "secret1", testMethod))
+	if err != nil || appId != "app1" {
+		t.Fatalf("expect app1 actual appId=%s err=%v", appId, err)
+	}
+
+	if _, err := callServer(interceptor, signedIncomingContext("app1", "wrong", testMethod)); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expect Unauthenticated for bad signature actual %v", err)
+	}
+	if _, err := callServer(interceptor, signedIncomingContext("app1", "secret1", "/other/Method")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expect Unauthenticated for other method signature actual %v", err)
+	}
+	if _, err := callServer(interceptor, context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expect Unauthenticated for missing metadata actual %v", err)
+	}
+}
+
+func TestVerifyStaleTimestamp(t *testing.T) {
+	interceptor := UnaryServerInterceptor(WithSecret("app1", "secret1"), WithMaxSkew(time.Minute))
+	timestamp := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		AppIdKey, "app1",
+		TimestampKey, timestamp,
+		SignatureKey, Sign("secret1", testMethod, timestamp),
+	))
+	if _, err := callServer(interceptor, ctx); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expect Unauthenticated actual %v", err)
+	}
+}
+
+func TestVerifyAllowList(t *testing.T) {
+	interceptor := UnaryServerInterceptor(WithSecret("app1", "secret1"), WithSecret("app2", "secret2"), WithAllow(testMethod, "app1"))
+
+	if _, err := callServer(interceptor, signedIncomingContext("app1", "secret1", testMethod)); err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	if _, err := callServer(interceptor, signedIncomingContext("app2", "secret2", testMethod)); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expect PermissionDenied actual %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+)
+
+type options struct {
+	defaultTimeout time.Duration
+	methodTimeouts map[string]time.Duration
+}
+
+type OptionsFunc func(*options)
+
+// WithDefaultTimeout 客户端没有设置deadline时使用的超时，默认0不设置
+func WithDefaultTimeout(d time.Duration) OptionsFunc {
+	return func(o *options) {
+		o.defaultTimeout = d
+	}
+}
+
+// WithMethodTimeout 方法的最大超时，客户端的deadline更长时会被缩短，没有deadline时也使用这个值，
+// d<=0表示这个方法不设置超时，也不使用WithDefaultTimeout
+func WithMethodTimeout(method string, d time.Duration) OptionsFunc {
+	return func(o *options) {
+		o.methodTimeouts[method] = d
+	}
+}
+
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{methodTimeouts: make(map[string]time.Duration)}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
+
+// UnaryServerInterceptor 限制handler的执行时间，handler中使用ctx调用httplib、mysql、redigo等下游时会受到同一个deadline的约束
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, cancel := opts.withTimeout(ctx, info.FullMethod)
+		defer cancel()
+		return handler(newCtx, req)
+	}
+}
+
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, cancel := opts.withTimeout(stream.Context(), info.FullMethod)
+		defer cancel()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
+
+func (o options) withTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	timeout, ok := o.methodTimeouts[method]
+	if !ok {
+		// 没有配置方法超时，只在客户端没有deadline时使用默认值
+		if _, hasDeadline := ctx.Deadline(); hasDeadline || o.defaultTimeout <= 0 {
+			return ctx, func() {}
+		}
+		timeout = o.defaultTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	// 客户端的deadline更短时WithTimeout会保留原来的deadline
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Remaining 剩余的时间预算，没有deadline时第二个返回值为false
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(d), true
+}
@@ -0,0 +1,54 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func remainingInHandler(interceptor grpc.UnaryServerInterceptor, ctx context.Context, method string) (time.Duration, bool) {
+	var (
+		remaining time.Duration
+		ok        bool
+	)
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		remaining, ok = Remaining(ctx)
+		return nil, nil
+	})
+	return remaining, ok
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(WithDefaultTimeout(time.Second), WithMethodTimeout("/svc/Slow", 100*time.Millisecond))
+
+	// 没有deadline时使用默认值
+	if remaining, ok := remainingInHandler(interceptor, context.Background(), "/svc/Fast"); !ok || remaining > time.Second || remaining < 900*time.Millisecond {
+		t.Errorf("expect default timeout actual %v %v", remaining, ok)
+	}
+
+	// 客户端deadline比方法超时长时被缩短
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if remaining, ok := remainingInHandler(interceptor, ctx, "/svc/Slow"); !ok || remaining > 100*time.Millisecond {
+		t.Errorf("expect method timeout actual %v %v", remaining, ok)
+	}
+
+	// 客户端已有deadline且没有方法超时时保持不变
+	if remaining, ok := remainingInHandler(interceptor, ctx, "/svc/Fast"); !ok || remaining < 50*time.Second {
+		t.Errorf("expect client deadline actual %v %v", remaining, ok)
+	}
+}
+
+func TestNoTimeout(t *testing.T) {
+	if _, ok := remainingInHandler(UnaryServerInterceptor(), context.Background(), "/svc/Fast"); ok {
+		t.Errorf("expect no deadline")
+	}
+
+	// 方法超时为0时不设置超时，而不是立即过期
+	interceptor := UnaryServerInterceptor(WithDefaultTimeout(time.Second), WithMethodTimeout("/svc/Stream", 0))
+	if _, ok := remainingInHandler(interceptor, context.Background(), "/svc/Stream"); ok {
+		t.Errorf("expect no deadline for zero method timeout")
+	}
+}
@@ -0,0 +1,117 @@
+package header
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/zer0131/toolbox"
+	"github.com/zer0131/toolbox/log"
+	"github.com/zer0131/toolbox/stat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor 客户端拦截器，在cron、http handler等没有经过服务端拦截器的场景下
+// 也能把log-id和remote-addr带给下游，同时按方法上报调用耗时与状态码
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		startTime := time.Now()
+		err := invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+		clientStat(method, cc.Target(), startTime, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor 同UnaryClientInterceptor，stream在RecvMsg返回错误(包括io.EOF)时上报，
+// 服务端只返回一个消息的client stream在第一次RecvMsg成功时上报
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		startTime := time.Now()
+		cs, err := streamer(outgoingContext(ctx), desc, cc, method, opts...)
+		if err != nil {
+			clientStat(method, cc.Target(), startTime, err)
+			return nil, err
+		}
+		return &statClientStream{
+			ClientStream:  cs,
+			method:        method,
+			target:        cc.Target(),
+			startTime:     startTime,
+			serverStreams: desc.ServerStreams,
+		}, nil
+	}
+}
+
+// outgoingContext 补齐outgoing metadata中的log-id和remote-addr，已经存在的不覆盖
+func outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var kv []string
+	if len(md.Get(log.LogIDKey)) == 0 {
+		kv = append(kv, log.LogIDKey, clientLogId(ctx))
+	}
+	if len(md.Get(log.RemoteAddrName)) == 0 {
+		kv = append(kv, log.RemoteAddrName, clientRemoteAddr(ctx))
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// clientLogId 优先使用header拦截器确定的log-id，没有经过header拦截器时从incoming metadata中解析
+func clientLogId(ctx context.Context) string {
+	if logId, ok := log.LogIdFromContext(ctx); ok && logId != "" {
+		return logId
+	}
+	if logId := LogIdFromIncoming(ctx); logId != "" {
+		return logId
+	}
+	return log.GenLogId()
+}
+
+// 优先透传最初的调用方地址，本机发起的调用使用本机ip
+func clientRemoteAddr(ctx context.Context) string {
+	if addr := log.RemoteAddrNameFromContext(ctx); addr != "" {
+		return addr
+	}
+	if addr, ok := ctx.Value(log.RemoteAddrName).(string); ok && addr != "" {
+		return addr
+	}
+	return toolbox.LocalIP()
+}
+
+func clientStat(method, target string, startTime time.Time, err error) {
+	stat.ClientStatV2(stat.Labels{
+		Client: stat.Grpc,
+		Op:     method,
+		Addr:   target,
+		Code:   status.Code(err).String(),
+	}, startTime)
+}
+
+type statClientStream struct {
+	grpc.ClientStream
+	method        string
+	target        string
+	startTime     time.Time
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *statClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	// 服务端不是stream时调用方收到响应后不会再RecvMsg，不能等io.EOF
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() {
+			statErr := err
+			if statErr == io.EOF {
+				statErr = nil
+			}
+			clientStat(s.method, s.target, s.startTime, statErr)
+		})
+	}
+	return err
+}
@@ -2,110 +2,128 @@ package header
 
 import (
 	"context"
-	"fmt"
+	"runtime"
+
 	"github.com/grpc-ecosystem/go-grpc-middleware"
-	"github.com/zer0131/toolbox"
 	"github.com/zer0131/toolbox/log"
+	"github.com/zer0131/toolbox/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
-	"math/rand"
-	"strings"
-	"time"
+	"google.golang.org/grpc/status"
+)
+
+// 除log-id外，也接受这些header作为log-id的来源
+const (
+	RequestIdKey   = "x-request-id"
+	TraceParentKey = trace.TraceParentKey
 )
 
-func createLogId() string {
-	t := time.Now().UnixNano() / 1000000
-	r := rand.Intn(10000)
-	return fmt.Sprintf("%d%d", t, r)
+// IdGenerator 请求中没有log-id时用来生成
+type IdGenerator func() string
+
+type options struct {
+	generator IdGenerator
 }
 
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-
-		defer toolbox.CatchPanic(ctx, req)
-
-		// ctx在grpc app中一层层传递，可能还要传递给其他grpc app
-		// 所以之类直接将ctx初始化好
-		m := make(map[string]string)
-
-		// logId
-		var logId string
-		md, ok := metadata.FromIncomingContext(ctx)
-		if ok {
-			// 优先看是否有Log-Id，外部访问
-			v, ok := md[strings.ToLower(log.LogIDKey)]
-			if ok {
-				logId = v[0]
-			}
-
-			// 然后再看是否是内部grpc之间调用
-			if logId == "" {
-				v, ok = md[log.LogIDKey]
-				if ok {
-					logId = v[0]
-				}
-			}
-		}
-		if logId == "" {
-			logId = createLogId()
-		}
-		m[log.LogIDKey] = logId
-
-		// remoteAddr
-		var remoteAddr string
-		p, ok := peer.FromContext(ctx)
-		if ok {
-			m[log.RemoteAddrName] = p.Addr.String()
-			remoteAddr = p.Addr.String()
-		}
-		outgoingMd := metadata.New(m)
+type OptionsFunc func(*options)
 
-		newCtx := metadata.NewOutgoingContext(ctx, outgoingMd)
+// WithIdGenerator 自定义log-id的生成方式，默认log.GenLogId
+func WithIdGenerator(g IdGenerator) OptionsFunc {
+	return func(o *options) {
+		o.generator = g
+	}
+}
 
-		// 放到ctxValue中的原因是，util中的log库需要这个logid
-		newCtx = context.WithValue(newCtx, log.LogIDKey, logId)
-		newCtx = context.WithValue(newCtx, log.RemoteAddrName, remoteAddr)
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{generator: log.GenLogId}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
 
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		newCtx := opts.newContext(ctx)
+		defer catchPanic(newCtx, req, &err)
 		return handler(newCtx, req)
 	}
 }
 
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
-	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-
-		// ctx在grpc app中一层层传递，可能还要传递给其他grpc app
-		// 所以之类直接将ctx初始化好
-		m := make(map[string]string)
-
-		// logId
-		var logId string
-		md, ok := metadata.FromIncomingContext(stream.Context())
-		if ok {
-			v, ok := md[log.LogIDKey]
-			if ok {
-				logId = v[0]
-			}
-		}
-		if logId == "" {
-			logId = createLogId()
-		}
-		m[log.LogIDKey] = logId
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = opts.newContext(stream.Context())
+		defer catchPanic(wrapped.WrappedContext, nil, &err)
+		return handler(srv, wrapped)
+	}
+}
 
-		// remoteAddr
-		p, ok := peer.FromContext(stream.Context())
-		if ok {
-			m[log.RemoteAddrName] = p.Addr.String()
-		}
-		outgoingMd := metadata.New(m)
+// catchPanic 兜底捕获后续拦截器和handler中的panic，带log-id记录堆栈并返回codes.Internal，
+// 需要panic计数或自定义错误时，把recovery拦截器放在header之后
+func catchPanic(ctx context.Context, req interface{}, err *error) {
+	if p := recover(); p != nil {
+		var buf [4096]byte
+		n := runtime.Stack(buf[:], false)
+		log.Errorf(ctx, "panic req:%+v err:%+v", req, p)
+		log.Errorf(ctx, "%s", string(buf[:n]))
+		*err = status.Errorf(codes.Internal, "panic: %v", p)
+	}
+}
 
-		newCtx := metadata.NewOutgoingContext(stream.Context(), outgoingMd)
+// newContext unary和stream共用：
+// ctx在grpc app中一层层传递，可能还要传递给其他grpc app，所以这里直接将ctx初始化好
+func (o options) newContext(ctx context.Context) context.Context {
+	logId := LogIdFromIncoming(ctx)
+	if logId == "" {
+		logId = o.generator()
+	}
 
-		// 放到ctxValue中的原因是，util中的log库需要这个logid
-		newCtx = context.WithValue(newCtx, log.LogIDKey, logId)
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
 
-		wrapped := grpc_middleware.WrapServerStream(stream)
-		wrapped.WrappedContext = newCtx
-		return handler(srv, wrapped)
+	outgoingMd := metadata.New(map[string]string{log.LogIDKey: logId})
+	if remoteAddr != "" {
+		outgoingMd.Set(log.RemoteAddrName, remoteAddr)
+	}
+	newCtx := metadata.NewOutgoingContext(ctx, outgoingMd)
+
+	// 放到ctxValue中的原因是，util中的log库需要这个logid
+	newCtx = context.WithValue(newCtx, log.LogIDKey, logId)
+	newCtx = context.WithValue(newCtx, log.RemoteAddrName, remoteAddr)
+	return newCtx
+}
+
+// LogIdFromIncoming 从incoming metadata中取log-id，依次尝试：
+// log-id、Log-Id（手动构造的metadata没有转为小写）、x-request-id、traceparent中的trace-id
+func LogIdFromIncoming(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{log.LogIDKey, "Log-Id", RequestIdKey} {
+		if v := md[key]; len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if v := md[TraceParentKey]; len(v) > 0 {
+		return TraceIdFromTraceParent(v[0])
+	}
+	return ""
+}
+
+// TraceIdFromTraceParent 解析W3C traceparent，例如00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01，
+// 格式不合法时返回空
+func TraceIdFromTraceParent(traceParent string) string {
+	sc, ok := trace.ParseTraceParent(traceParent)
+	if !ok {
+		return ""
 	}
+	return sc.TraceID.String()
 }
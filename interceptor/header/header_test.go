@@ -1 +1,198 @@
 package header
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zer0131/toolbox/log"
+	"github.com/zer0131/toolbox/stat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type statRecorder struct {
+	labels []stat.Labels
+}
+
+func (r *statRecorder) Report(labels stat.Labels, cost time.Duration) {
+	r.labels = append(r.labels, labels)
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	rec := &statRecorder{}
+	stat.RegisterReporter(rec)
+	defer stat.ResetReporters()
+
+	cc, err := grpc.Dial("127.0.0.1:1", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial err: %s", err)
+	}
+	defer cc.Close()
+
+	ctx := log.NewContextWithSpecifyLogID(context.Background(), "123")
+	method := "/helloworld.Greeter/SayHello"
+	err = UnaryClientInterceptor()(ctx, method, nil, nil, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if v := md.Get(log.LogIDKey); len(v) != 1 || v[0] != "123" {
+			t.Errorf("expect log-id 123 actual %v", v)
+		}
+		if v := md.Get(log.RemoteAddrName); len(v) != 1 {
+			t.Errorf("expect one remote-addr actual %v", v)
+		}
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(rec.labels) != 1 {
+		t.Fatalf("expect 1 stat actual %d", len(rec.labels))
+	}
+	expect := stat.Labels{Client: stat.Grpc, Op: method, Addr: cc.Target(), Code: codes.Unavailable.String()}
+	if rec.labels[0] != expect {
+		t.Errorf("expect %+v actual %+v", expect, rec.labels[0])
+	}
+}
+
+func TestOutgoingContextKeepExisting(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), log.LogIDKey, "abc", log.RemoteAddrName, "1.1.1.1:80")
+	md, _ := metadata.FromOutgoingContext(outgoingContext(ctx))
+	if v := md.Get(log.LogIDKey); len(v) != 1 || v[0] != "abc" {
+		t.Errorf("expect log-id abc actual %v", v)
+	}
+	if v := md.Get(log.RemoteAddrName); len(v) != 1 || v[0] != "1.1.1.1:80" {
+		t.Errorf("expect remote-addr 1.1.1.1:80 actual %v", v)
+	}
+}
+
+func TestOutgoingContextLogIdPriority(t *testing.T) {
+	// ctx中已经确定的log-id优先于incoming metadata中的x-request-id
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIdKey, "req-1"))
+	ctx := context.WithValue(incoming, log.LogIDKey, "123")
+	md, _ := metadata.FromOutgoingContext(outgoingContext(ctx))
+	if v := md.Get(log.LogIDKey); len(v) != 1 || v[0] != "123" {
+		t.Errorf("expect log-id 123 actual %v", v)
+	}
+
+	md, _ = metadata.FromOutgoingContext(outgoingContext(incoming))
+	if v := md.Get(log.LogIDKey); len(v) != 1 || v[0] != "req-1" {
+		t.Errorf("expect log-id req-1 actual %v", v)
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestLogIdFromIncoming(t *testing.T) {
+	var tests = []struct {
+		md     metadata.MD
+		expect string
+	}{
+		{md: metadata.Pairs(log.LogIDKey, "1", RequestIdKey, "2"), expect: "1"},
+		{md: metadata.MD{"Log-Id": []string{"3"}}, expect: "3"},
+		{md: metadata.Pairs(RequestIdKey, "2"), expect: "2"},
+		{md: metadata.Pairs(TraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), expect: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{md: metadata.Pairs(TraceParentKey, "00-00000000000000000000000000000000-00f067aa0ba902b7-01"), expect: ""},
+		{md: metadata.Pairs(TraceParentKey, "bad"), expect: ""},
+		{md: metadata.Pairs(TraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e47364bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), expect: ""},
+	}
+	for i, tt := range tests {
+		if actual := LogIdFromIncoming(metadata.NewIncomingContext(context.Background(), tt.md)); actual != tt.expect {
+			t.Errorf("Index %d expect %s actual %s", i, tt.expect, actual)
+		}
+	}
+}
+
+func TestServerInterceptorsConsistent(t *testing.T) {
+	opt := WithIdGenerator(func() string { return "generated" })
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("::1"), Port: 1234}})
+
+	check := func(name string, ctx context.Context) {
+		if v, _ := ctx.Value(log.LogIDKey).(string); v != "generated" {
+			t.Errorf("%s expect log-id generated actual %s", name, v)
+		}
+		if v, _ := ctx.Value(log.RemoteAddrName).(string); v != "[::1]:1234" {
+			t.Errorf("%s expect remote-addr [::1]:1234 actual %s", name, v)
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if v := md.Get(log.LogIDKey); len(v) != 1 || v[0] != "generated" {
+			t.Errorf("%s expect outgoing log-id generated actual %v", name, v)
+		}
+	}
+
+	_, _ = UnaryServerInterceptor(opt)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		check("unary", ctx)
+		return nil, nil
+	})
+	_ = StreamServerInterceptor(opt)(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		check("stream", stream.Context())
+		return nil
+	})
+}
+
+func TestServerInterceptorsCatchPanic(t *testing.T) {
+	logs := log.Capture(t)
+	opt := WithIdGenerator(func() string { return "generated" })
+
+	resp, err := UnaryServerInterceptor(opt)(context.Background(), "req", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Errorf("unary expect Internal actual resp=%v err=%v", resp, err)
+	}
+	err = StreamServerInterceptor(opt)(nil, &fakeServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("stream expect Internal actual %v", err)
+	}
+
+	if n := len(logs.Entries(log.ByLogId("generated"), log.ByContains("panic req"))); n != 2 {
+		t.Errorf("expect 2 panic logs with log-id actual %d", n)
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error { return nil }
+
+func TestStreamClientInterceptorClientStream(t *testing.T) {
+	rec := &statRecorder{}
+	stat.RegisterReporter(rec)
+	defer stat.ResetReporters()
+
+	cc, err := grpc.Dial("127.0.0.1:1", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial err: %s", err)
+	}
+	defer cc.Close()
+
+	method := "/helloworld.Greeter/SayHelloClientStream"
+	desc := &grpc.StreamDesc{ClientStreams: true}
+	cs, err := StreamClientInterceptor()(context.Background(), desc, cc, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	// CloseAndRecv只会RecvMsg一次
+	if err := cs.RecvMsg(nil); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	expect := stat.Labels{Client: stat.Grpc, Op: method, Addr: cc.Target(), Code: codes.OK.String()}
+	if len(rec.labels) != 1 || rec.labels[0] != expect {
+		t.Errorf("expect %+v actual %+v", expect, rec.labels)
+	}
+}
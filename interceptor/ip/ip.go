@@ -2,26 +2,134 @@ package ip
 
 import (
 	"context"
-	"errors"
+	"net"
+	"strings"
+
 	"github.com/zer0131/toolbox/ip"
 	"github.com/zer0131/toolbox/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+// DefaultForwardedKey 可信代理转发真实客户端ip时使用的metadata
+const DefaultForwardedKey = "x-forwarded-for"
+
+type options struct {
+	trustedProxies []*net.IPNet
+	forwardedKey   string
+}
+
+type OptionsFunc func(*options)
+
+// WithTrustedProxies 可信代理，支持具体ip和CIDR，来自可信代理的请求使用metadata中转发的客户端ip做校验
+func WithTrustedProxies(proxies ...string) OptionsFunc {
+	return func(o *options) {
+		for _, p := range proxies {
+			if !strings.Contains(p, "/") {
+				if pip := net.ParseIP(p); pip != nil && pip.To4() != nil {
+					p += "/32"
+				} else {
+					p += "/128"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(p)
+			if err != nil {
+				log.Errorf(context.Background(), "Illegal trusted proxy %s", p)
+				continue
+			}
+			o.trustedProxies = append(o.trustedProxies, ipNet)
+		}
+	}
+}
+
+// WithForwardedKey 转发客户端ip的metadata，默认x-forwarded-for，
+// 多级代理时从右往左跳过可信代理，取第一个不可信的ip
+func WithForwardedKey(key string) OptionsFunc {
+	return func(o *options) {
+		o.forwardedKey = key
+	}
+}
+
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{forwardedKey: DefaultForwardedKey}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
+
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := opts.check(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
 
-		var remoteAddr string
-		p, ok := peer.FromContext(ctx)
-		if ok {
-			remoteAddr = p.Addr.String()
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := opts.check(stream.Context()); err != nil {
+			return err
 		}
-		if !ip.CheckIp(ctx, remoteAddr) {
-			log.Warnf(ctx, "Unauthorized ip[%s]", remoteAddr)
-			return nil, errors.New("Ip unauthorized")
+		return handler(srv, stream)
+	}
+}
+
+// check 配置了白名单时，不在白名单中或者取不到合法的客户端ip返回codes.PermissionDenied，
+// 没有配置白名单时全部放行
+func (o options) check(ctx context.Context) error {
+	if !ip.Enabled() {
+		return nil
+	}
+	clientIp := o.clientIp(ctx)
+	if net.ParseIP(clientIp) == nil || !ip.CheckIp(ctx, clientIp) {
+		log.Warnf(ctx, "Unauthorized ip[%s]", clientIp)
+		return status.Error(codes.PermissionDenied, "Ip unauthorized")
+	}
+	return nil
+}
+
+func (o options) clientIp(ctx context.Context) string {
+	var peerIp string
+	if p, ok := peer.FromContext(ctx); ok {
+		peerIp = ip.SplitHost(p.Addr.String())
+	}
+	if !o.trusted(peerIp) {
+		return peerIp
+	}
+
+	// 最右边的ip是离自己最近的代理追加的，左边的可能被客户端伪造，
+	// 所以从右往左跳过可信代理，第一个不可信的ip才是客户端ip
+	md, _ := metadata.FromIncomingContext(ctx)
+	var forwarded []string
+	for _, v := range md.Get(o.forwardedKey) {
+		forwarded = append(forwarded, strings.Split(v, ",")...)
+	}
+	clientIp := peerIp
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		clientIp = ip.SplitHost(forwarded[i])
+		if !o.trusted(clientIp) {
+			break
 		}
+	}
+	return clientIp
+}
 
-		return handler(ctx, req)
+func (o options) trusted(peerIp string) bool {
+	pip := net.ParseIP(peerIp)
+	if pip == nil {
+		return false
+	}
+	for _, ipNet := range o.trustedProxies {
+		if ipNet.Contains(pip) {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,125 @@
+package ip
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/zer0131/toolbox/ip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func peerContext(addr net.Addr) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+}
+
+func TestInterceptors(t *testing.T) {
+	ip.Init(context.Background(), []string{"10.188.0.0/16", "::1"})
+	t.Cleanup(ip.Reset)
+
+	opt := WithTrustedProxies("192.168.0.1")
+	unary := UnaryServerInterceptor(opt)
+	stream := StreamServerInterceptor(opt)
+	call := func(ctx context.Context) (error, error) {
+		_, unaryErr := unary(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		streamErr := stream(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+			return nil
+		})
+		return unaryErr, streamErr
+	}
+
+	var tests = []struct {
+		name   string
+		ctx    context.Context
+		expect codes.Code
+	}{
+		{
+			name:   "ipv4 in cidr",
+			ctx:    peerContext(&net.TCPAddr{IP: net.ParseIP("10.188.0.24"), Port: 1234}),
+			expect: codes.OK,
+		},
+		{
+			name:   "ipv6 loopback",
+			ctx:    peerContext(&net.TCPAddr{IP: net.ParseIP("::1"), Port: 1234}),
+			expect: codes.OK,
+		},
+		{
+			name:   "not in white list",
+			ctx:    peerContext(&net.TCPAddr{IP: net.ParseIP("10.189.0.24"), Port: 1234}),
+			expect: codes.PermissionDenied,
+		},
+		{
+			name: "forwarded by trusted proxy",
+			ctx: metadata.NewIncomingContext(peerContext(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 1234}),
+				metadata.Pairs(DefaultForwardedKey, "10.188.0.24")),
+			expect: codes.OK,
+		},
+		{
+			name: "forwarded by untrusted proxy",
+			ctx: metadata.NewIncomingContext(peerContext(&net.TCPAddr{IP: net.ParseIP("192.168.0.2"), Port: 1234}),
+				metadata.Pairs(DefaultForwardedKey, "10.188.0.24")),
+			expect: codes.PermissionDenied,
+		},
+		{
+			name: "spoofed leftmost forwarded ip",
+			ctx: metadata.NewIncomingContext(peerContext(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 1234}),
+				metadata.Pairs(DefaultForwardedKey, "10.188.0.24, 10.189.0.24")),
+			expect: codes.PermissionDenied,
+		},
+		{
+			name: "forwarded through multiple trusted proxies",
+			ctx: metadata.NewIncomingContext(peerContext(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 1234}),
+				metadata.Pairs(DefaultForwardedKey, "10.189.0.24, 10.188.0.24, 192.168.0.1")),
+			expect: codes.OK,
+		},
+		{
+			name: "invalid forwarded ip",
+			ctx: metadata.NewIncomingContext(peerContext(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 1234}),
+				metadata.Pairs(DefaultForwardedKey, "unknown")),
+			expect: codes.PermissionDenied,
+		},
+		{
+			name:   "no peer",
+			ctx:    context.Background(),
+			expect: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		unaryErr, streamErr := call(tt.ctx)
+		if status.Code(unaryErr) != tt.expect || status.Code(streamErr) != tt.expect {
+			t.Errorf("%s expect %s actual unary=%v stream=%v", tt.name, tt.expect, unaryErr, streamErr)
+		}
+	}
+}
+
+func TestEmptyWhiteList(t *testing.T) {
+	ip.Reset()
+
+	ctx := peerContext(&net.UnixAddr{Name: "/tmp/grpc.sock", Net: "unix"})
+	_, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Errorf("expect no error without white list, actual %v", err)
+	}
+	err = StreamServerInterceptor()(nil, &fakeServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expect no error without white list, actual %v", err)
+	}
+}
@@ -1,77 +1,117 @@
 package perfcounter
 
 import (
-	"fmt"
-	"net"
-	"strings"
-
 	"context"
+	"time"
+
 	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 指标名以toolbox.StatProtoMetrix(FullMethod)为前缀，例如helloworld.Greeter.SayHello.10.0.0.1：
+//
+//	<name>              timer，请求数与耗时分布
+//	<name>.code.<Code>  meter，按grpc状态码计数，例如.code.OK
+//	<name>.inflight     counter，正在处理的请求数
+//	<name>.recv         meter，stream收到的消息数
+//	<name>.sent         meter，stream发送的消息数
+//
+// 配合prometheusmetrics.NewPrometheusProvider即可导出到prometheus
+const (
+	suffixCode     = ".code."
+	suffixInflight = ".inflight"
+	suffixRecv     = ".recv"
+	suffixSent     = ".sent"
 )
 
-var localIp string
+// handler panic时按codes.Internal计数，panic本身交给recovery或header拦截器处理
+var errPanic = status.Error(codes.Internal, "panic")
 
-func getLocalIP() string {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return ""
-	}
-	for _, address := range addrs {
-		// check the address type and if it is not a loopback the display it
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return ipnet.IP.String()
-			}
-		}
-	}
-	return ""
+type options struct {
+	registry metrics.Registry
 }
 
-// /helloworld.Greeter/SayHello
-func metrix(method string) string {
-	if localIp == "" {
-		localIp = getLocalIP()
-		if localIp == "" {
-			localIp = "unknown"
-		}
+type OptionsFunc func(*options)
+
+// WithRegistry 指标写入的registry，默认metrics.DefaultRegistry
+func WithRegistry(r metrics.Registry) OptionsFunc {
+	return func(o *options) {
+		o.registry = r
 	}
-	return fmt.Sprintf("%s.%s", strings.Replace(method[1:], "/", ".", -1), localIp)
 }
 
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-
-		//name := metrix(info.FullMethod)
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{registry: metrics.DefaultRegistry}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
 
-		// qps统计：60s上传一次，可以观察75% 95% 99%三个指标
-		//pfc.Meter(name, 1)
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		name := toolbox.StatProtoMetrix(info.FullMethod)
+		done := opts.begin(name)
+		// handler panic时err保持errPanic，inflight同样会减少
+		err = errPanic
+		defer func() { done(err) }()
+		return handler(ctx, req)
+	}
+}
 
-		// 耗时统计
-		//start := time.Now()
-		//defer func() {
-		//pfc.Histogram(name, time.Since(start).Nanoseconds()/(1000*1000))
-		//}()
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		name := toolbox.StatProtoMetrix(info.FullMethod)
+		done := opts.begin(name)
+		err = errPanic
+		defer func() { done(err) }()
 
-		return handler(ctx, req)
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = stream.Context()
+		return handler(srv, &countingStream{
+			WrappedServerStream: wrapped,
+			recv:                metrics.GetOrRegisterMeter(name+suffixRecv, opts.registry),
+			sent:                metrics.GetOrRegisterMeter(name+suffixSent, opts.registry),
+		})
 	}
 }
 
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
-	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		//name := metrix(info.FullMethod)
+// begin 记录开始处理，返回的函数在处理结束时调用
+func (o options) begin(name string) func(err error) {
+	inflight := metrics.GetOrRegisterCounter(name+suffixInflight, o.registry)
+	inflight.Inc(1)
+	start := time.Now()
+	return func(err error) {
+		inflight.Dec(1)
+		metrics.GetOrRegisterTimer(name, o.registry).UpdateSince(start)
+		metrics.GetOrRegisterMeter(name+suffixCode+status.Code(err).String(), o.registry).Mark(1)
+	}
+}
 
-		// qps统计：60s上传一次，可以观察75% 95% 99%三个指标
-		//pfc.Meter(name, 1)
+type countingStream struct {
+	*grpc_middleware.WrappedServerStream
+	recv metrics.Meter
+	sent metrics.Meter
+}
 
-		// 耗时统计
-		//start := time.Now()
-		//defer func() {
-		//pfc.Histogram(name, time.Since(start).Nanoseconds()/(1000*1000))
-		//}()
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.WrappedServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Mark(1)
+	}
+	return err
+}
 
-		wrapped := grpc_middleware.WrapServerStream(stream)
-		wrapped.WrappedContext = stream.Context()
-		return handler(srv, wrapped)
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.WrappedServerStream.RecvMsg(m)
+	if err == nil {
+		s.recv.Mark(1)
 	}
+	return err
 }
@@ -1 +1,93 @@
 package perfcounter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *fakeServerStream) Context() context.Context    { return context.Background() }
+func (s *fakeServerStream) SendMsg(m interface{}) error { return nil }
+func (s *fakeServerStream) RecvMsg(m interface{}) error { return nil }
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	name := toolbox.StatProtoMetrix(info.FullMethod)
+
+	_, _ = UnaryServerInterceptor(WithRegistry(r))(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if v := r.Get(name + suffixInflight).(metrics.Counter).Count(); v != 1 {
+			t.Errorf("expect 1 inflight actual %d", v)
+		}
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+
+	if v := r.Get(name).(metrics.Timer).Count(); v != 1 {
+		t.Errorf("expect 1 request actual %d", v)
+	}
+	if v := r.Get(name + suffixCode + codes.NotFound.String()).(metrics.Meter).Count(); v != 1 {
+		t.Errorf("expect 1 NotFound actual %d", v)
+	}
+	if v := r.Get(name + suffixInflight).(metrics.Counter).Count(); v != 0 {
+		t.Errorf("expect 0 inflight actual %d", v)
+	}
+}
+
+func TestUnaryServerInterceptorPanic(t *testing.T) {
+	r := metrics.NewRegistry()
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	name := toolbox.StatProtoMetrix(info.FullMethod)
+
+	func() {
+		defer func() {
+			if p := recover(); p == nil {
+				t.Errorf("expect panic to be propagated")
+			}
+		}()
+		_, _ = UnaryServerInterceptor(WithRegistry(r))(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if v := r.Get(name + suffixInflight).(metrics.Counter).Count(); v != 0 {
+		t.Errorf("expect 0 inflight actual %d", v)
+	}
+	if v := r.Get(name + suffixCode + codes.Internal.String()).(metrics.Meter).Count(); v != 1 {
+		t.Errorf("expect 1 Internal actual %d", v)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	info := &grpc.StreamServerInfo{FullMethod: "/helloworld.Greeter/SayHelloStream"}
+	name := toolbox.StatProtoMetrix(info.FullMethod)
+
+	err := StreamServerInterceptor(WithRegistry(r))(nil, &fakeServerStream{}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		_ = stream.RecvMsg(nil)
+		_ = stream.SendMsg(nil)
+		_ = stream.SendMsg(nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	if v := r.Get(name + suffixRecv).(metrics.Meter).Count(); v != 1 {
+		t.Errorf("expect 1 recv actual %d", v)
+	}
+	if v := r.Get(name + suffixSent).(metrics.Meter).Count(); v != 2 {
+		t.Errorf("expect 2 sent actual %d", v)
+	}
+	if v := r.Get(name + suffixCode + codes.OK.String()).(metrics.Meter).Count(); v != 1 {
+		t.Errorf("expect 1 OK actual %d", v)
+	}
+}
@@ -0,0 +1,347 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/zer0131/toolbox/interceptor/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 拒绝的原因，作为codes.ResourceExhausted的message，同时传给RejectedFunc
+const (
+	CodeMethodLimited   = "method_limited"
+	CodeCallerLimited   = "caller_limited"
+	CodeInflightLimited = "inflight_limited"
+)
+
+// 并发超限时建议的重试间隔
+const inflightRetryDelay = 100 * time.Millisecond
+
+// DefaultMaxCallers 默认最多保存的调用方令牌桶个数
+const DefaultMaxCallers = 10000
+
+// Limit 令牌桶配置，Rate为每秒产生的令牌数，Burst为桶容量，Rate<=0表示不限制
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+// RejectedFunc 请求被拒绝时调用，method为FullMethod，code为拒绝的原因，
+// 例如prometheusmetrics.Metrics.ObserveRateLimit
+type RejectedFunc func(ctx context.Context, method, caller, code string)
+
+type options struct {
+	onRejected   RejectedFunc
+	methodLimits map[string]Limit
+	callerLimit  Limit
+	callerKey    string
+	maxCallers   int
+	maxInflight  int64
+}
+
+type OptionsFunc func(*options)
+
+// WithMethodLimit 按FullMethod限流，例如/helloworld.Greeter/SayHello
+func WithMethodLimit(method string, limit Limit) OptionsFunc {
+	return func(o *options) {
+		o.methodLimits[method] = limit
+	}
+}
+
+// WithCallerLimit 每个调用方的限流，所有方法共用
+func WithCallerLimit(limit Limit) OptionsFunc {
+	return func(o *options) {
+		o.callerLimit = limit
+	}
+}
+
+// WithCallerKey 从incoming metadata的key中识别调用方。metadata由客户端填写，
+// 只能用于前面有可信网关覆盖该key的场景，否则客户端每次换一个值就能绕过调用方限流。
+// 默认使用auth拦截器校验通过的app-id（需要放在auth之后），没有时使用peer ip
+func WithCallerKey(key string) OptionsFunc {
+	return func(o *options) {
+		o.callerKey = key
+	}
+}
+
+// WithMaxCallers 最多保存的调用方令牌桶个数，默认DefaultMaxCallers，超过时淘汰最久没有请求的调用方
+func WithMaxCallers(n int) OptionsFunc {
+	return func(o *options) {
+		o.maxCallers = n
+	}
+}
+
+// WithMaxInflight 最大并发数，默认0不限制
+func WithMaxInflight(n int) OptionsFunc {
+	return func(o *options) {
+		o.maxInflight = int64(n)
+	}
+}
+
+// WithRejectedFunc 请求被拒绝时的回调，用于服务端的打点，默认不处理
+func WithRejectedFunc(fn RejectedFunc) OptionsFunc {
+	return func(o *options) {
+		o.onRejected = fn
+	}
+}
+
+// Limiter 限流器，所有限制都可以在运行时通过Set*修改
+type Limiter struct {
+	onRejected RejectedFunc
+
+	mutex         sync.RWMutex
+	methodLimits  map[string]Limit
+	methodBuckets map[string]*bucket
+
+	// 调用方令牌桶按LRU淘汰，命中时也要调整顺序，所以使用单独的互斥锁
+	callerMutex   sync.Mutex
+	callerLimit   Limit
+	callerKey     string
+	callerBuckets map[string]*list.Element
+	callerLRU     *list.List
+	maxCallers    int
+
+	maxInflight int64
+	inflight    int64
+}
+
+func NewLimiter(opt ...OptionsFunc) *Limiter {
+	opts := options{methodLimits: make(map[string]Limit), maxCallers: DefaultMaxCallers}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return &Limiter{
+		onRejected:    opts.onRejected,
+		methodLimits:  opts.methodLimits,
+		callerLimit:   opts.callerLimit,
+		callerKey:     opts.callerKey,
+		methodBuckets: make(map[string]*bucket),
+		callerBuckets: make(map[string]*list.Element),
+		callerLRU:     list.New(),
+		maxCallers:    opts.maxCallers,
+		maxInflight:   opts.maxInflight,
+	}
+}
+
+// SetMethodLimit 修改方法的限流，已有的令牌桶会被重建
+func (l *Limiter) SetMethodLimit(method string, limit Limit) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.methodLimits[method] = limit
+	delete(l.methodBuckets, method)
+}
+
+// SetCallerLimit 修改调用方的限流，已有的令牌桶会被重建
+func (l *Limiter) SetCallerLimit(limit Limit) {
+	l.callerMutex.Lock()
+	defer l.callerMutex.Unlock()
+	l.callerLimit = limit
+	l.callerBuckets = make(map[string]*list.Element)
+	l.callerLRU.Init()
+}
+
+// SetMaxInflight 修改最大并发数，0表示不限制
+func (l *Limiter) SetMaxInflight(n int) {
+	atomic.StoreInt64(&l.maxInflight, int64(n))
+}
+
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := l.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := l.acquire(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, stream)
+	}
+}
+
+// acquire 依次检查并发、方法、调用方的限制，通过时返回的release需要在处理结束后调用
+func (l *Limiter) acquire(ctx context.Context, method string) (func(), error) {
+	caller := l.caller(ctx)
+
+	n := atomic.AddInt64(&l.inflight, 1)
+	release := func() {
+		atomic.AddInt64(&l.inflight, -1)
+	}
+	if max := atomic.LoadInt64(&l.maxInflight); max > 0 && n > max {
+		release()
+		return nil, l.rejected(ctx, method, caller, CodeInflightLimited, inflightRetryDelay)
+	}
+
+	methodBucket := l.methodBucket(method)
+	if methodBucket != nil {
+		if ok, wait := methodBucket.take(time.Now()); !ok {
+			release()
+			return nil, l.rejected(ctx, method, caller, CodeMethodLimited, wait)
+		}
+	}
+	if b := l.callerBucket(caller); b != nil {
+		if ok, wait := b.take(time.Now()); !ok {
+			// 被调用方限制拒绝的请求不应该占用方法的配额
+			if methodBucket != nil {
+				methodBucket.refund()
+			}
+			release()
+			return nil, l.rejected(ctx, method, caller, CodeCallerLimited, wait)
+		}
+	}
+	return release, nil
+}
+
+func (l *Limiter) methodBucket(method string) *bucket {
+	l.mutex.RLock()
+	b, ok := l.methodBuckets[method]
+	limit, limited := l.methodLimits[method]
+	l.mutex.RUnlock()
+	if ok {
+		return b
+	}
+	if !limited || limit.unlimited() {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if b, ok = l.methodBuckets[method]; !ok {
+		b = newBucket(limit)
+		l.methodBuckets[method] = b
+	}
+	return b
+}
+
+type callerEntry struct {
+	caller string
+	bucket *bucket
+}
+
+func (l *Limiter) callerBucket(caller string) *bucket {
+	l.callerMutex.Lock()
+	defer l.callerMutex.Unlock()
+	if l.callerLimit.unlimited() {
+		return nil
+	}
+	if e, ok := l.callerBuckets[caller]; ok {
+		l.callerLRU.MoveToFront(e)
+		return e.Value.(*callerEntry).bucket
+	}
+
+	if l.maxCallers > 0 && l.callerLRU.Len() >= l.maxCallers {
+		oldest := l.callerLRU.Back()
+		l.callerLRU.Remove(oldest)
+		delete(l.callerBuckets, oldest.Value.(*callerEntry).caller)
+	}
+	b := newBucket(l.callerLimit)
+	l.callerBuckets[caller] = l.callerLRU.PushFront(&callerEntry{caller: caller, bucket: b})
+	return b
+}
+
+func (l *Limiter) caller(ctx context.Context) string {
+	if l.callerKey != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(l.callerKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if appId, ok := auth.AppIdFromContext(ctx); ok && appId != "" {
+		return appId
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// rejected 返回codes.ResourceExhausted，通过RetryInfo告知客户端多久之后重试
+func (l *Limiter) rejected(ctx context.Context, method, caller, code string, retryDelay time.Duration) error {
+	if l.onRejected != nil {
+		l.onRejected(ctx, method, caller, code)
+	}
+
+	st := status.New(codes.ResourceExhausted, code)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryDelay)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// bucket 令牌桶，按时间差补充令牌
+type bucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refund 归还take取走的令牌
+func (b *bucket) refund() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// take 取一个令牌，取不到时返回还需要等待的时间
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zer0131/toolbox/interceptor/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rejectedRecorder 记录RejectedFunc的参数
+type rejectedRecorder struct {
+	calls []string
+}
+
+func (r *rejectedRecorder) record(ctx context.Context, method, caller, code string) {
+	r.calls = append(r.calls, method+" "+caller+" "+code)
+}
+
+var okHandler = func(ctx context.Context, req interface{}) (interface{}, error) {
+	return "ok", nil
+}
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+}
+
+func TestMethodLimit(t *testing.T) {
+	rec := &rejectedRecorder{}
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	l := NewLimiter(WithMethodLimit(info.FullMethod, Limit{Rate: 1, Burst: 2}), WithRejectedFunc(rec.record))
+	interceptor := l.UnaryServerInterceptor()
+
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(peerContext("10.0.0.1"), nil, info, okHandler); err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+	}
+	_, err := interceptor(peerContext("10.0.0.1"), nil, info, okHandler)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expect ResourceExhausted actual %v", err)
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("expect retry info detail")
+	}
+	if _, ok := st.Details()[0].(*errdetails.RetryInfo); !ok {
+		t.Errorf("expect RetryInfo actual %T", st.Details()[0])
+	}
+
+	expect := info.FullMethod + " 10.0.0.1 " + CodeMethodLimited
+	if len(rec.calls) != 1 || rec.calls[0] != expect {
+		t.Errorf("expect %s actual %v", expect, rec.calls)
+	}
+
+	// 运行时放开限制
+	l.SetMethodLimit(info.FullMethod, Limit{})
+	if _, err := interceptor(peerContext("10.0.0.1"), nil, info, okHandler); err != nil {
+		t.Errorf("unexpected err after reset: %s", err)
+	}
+}
+
+func TestCallerLimit(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	interceptor := NewLimiter(WithCallerLimit(Limit{Rate: 1, Burst: 1}), WithCallerKey("app-id")).UnaryServerInterceptor()
+
+	ctxA := metadata.NewIncomingContext(peerContext("10.0.0.1"), metadata.Pairs("app-id", "a"))
+	ctxB := metadata.NewIncomingContext(peerContext("10.0.0.1"), metadata.Pairs("app-id", "b"))
+	if _, err := interceptor(ctxA, nil, info, okHandler); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if _, err := interceptor(ctxB, nil, info, okHandler); err != nil {
+		t.Fatalf("other caller should not be limited: %s", err)
+	}
+	if _, err := interceptor(ctxA, nil, info, okHandler); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expect ResourceExhausted actual %v", err)
+	}
+}
+
+func TestCallerLimitRefundMethodToken(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	interceptor := NewLimiter(
+		WithMethodLimit(info.FullMethod, Limit{Rate: 0.001, Burst: 2}),
+		WithCallerLimit(Limit{Rate: 0.001, Burst: 1}),
+		WithCallerKey("app-id"),
+	).UnaryServerInterceptor()
+
+	ctxA := metadata.NewIncomingContext(peerContext("10.0.0.1"), metadata.Pairs("app-id", "a"))
+	ctxB := metadata.NewIncomingContext(peerContext("10.0.0.1"), metadata.Pairs("app-id", "b"))
+	if _, err := interceptor(ctxA, nil, info, okHandler); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(ctxA, nil, info, okHandler); status.Convert(err).Message() != CodeCallerLimited {
+			t.Fatalf("expect caller limited actual %v", err)
+		}
+	}
+	// a被拒绝的请求没有消耗方法的令牌，b仍然可以调用
+	if _, err := interceptor(ctxB, nil, info, okHandler); err != nil {
+		t.Fatalf("method token should be refunded: %s", err)
+	}
+}
+
+func TestMaxCallers(t *testing.T) {
+	l := NewLimiter(WithCallerLimit(Limit{Rate: 0.001, Burst: 1}), WithMaxCallers(2))
+	for _, caller := range []string{"a", "b", "c"} {
+		if ok, _ := l.callerBucket(caller).take(time.Now()); !ok {
+			t.Fatalf("caller %s should not be limited", caller)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(l.callerBuckets) != 2 {
+		t.Fatalf("expect 2 caller buckets actual %d", len(l.callerBuckets))
+	}
+	if _, ok := l.callerBuckets["a"]; ok {
+		t.Errorf("expect least recently used caller a evicted")
+	}
+
+	// 命中的b移到最前，淘汰最久没有请求的c
+	l.callerBucket("b")
+	l.callerBucket("d")
+	if _, ok := l.callerBuckets["c"]; ok || len(l.callerBuckets) != 2 {
+		t.Errorf("expect caller c evicted actual %d buckets", len(l.callerBuckets))
+	}
+}
+
+func TestMaxInflight(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	l := NewLimiter(WithMaxInflight(1))
+	interceptor := l.UnaryServerInterceptor()
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptor(ctx, req, info, okHandler)
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expect ResourceExhausted actual %v", err)
+	}
+	if _, err := interceptor(context.Background(), nil, info, okHandler); err != nil {
+		t.Fatalf("inflight should be released: %s", err)
+	}
+}
+
+// signedContext 通过auth的客户端拦截器签名，转为服务端的incoming metadata
+func signedContext(t *testing.T, method string) context.Context {
+	var md metadata.MD
+	err := auth.UnaryClientInterceptor("a", "secret")(context.Background(), method, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.NewIncomingContext(peerContext("10.0.0.1"), md)
+}
+
+func TestCallerFromAuth(t *testing.T) {
+	l := NewLimiter(WithCallerLimit(Limit{Rate: 1, Burst: 1}))
+	authInterceptor := auth.UnaryServerInterceptor(auth.WithSecret("a", "secret"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	var caller string
+	_, err := authInterceptor(signedContext(t, info.FullMethod), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		caller = l.caller(ctx)
+		return nil, nil
+	})
+	if err != nil || caller != "a" {
+		t.Errorf("expect authenticated app-id as caller actual %s err %v", caller, err)
+	}
+}
@@ -0,0 +1,105 @@
+package recovery
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox"
+	"github.com/zer0131/toolbox/interceptor/header"
+	"github.com/zer0131/toolbox/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 每个方法的panic次数记录在<toolbox.StatProtoMetrix(FullMethod)>.panic
+const suffixPanic = ".panic"
+
+// HandlerFunc 把panic转换为返回给客户端的错误
+type HandlerFunc func(ctx context.Context, p interface{}) error
+
+type options struct {
+	handler  HandlerFunc
+	registry metrics.Registry
+}
+
+type OptionsFunc func(*options)
+
+// WithHandler 自定义panic的处理，默认返回codes.Internal，堆栈总是会记录到日志
+func WithHandler(h HandlerFunc) OptionsFunc {
+	return func(o *options) {
+		o.handler = h
+	}
+}
+
+// WithRegistry panic计数写入的registry，默认metrics.DefaultRegistry
+func WithRegistry(r metrics.Registry) OptionsFunc {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+func defaultHandler(ctx context.Context, p interface{}) error {
+	return status.Errorf(codes.Internal, "panic: %v", p)
+}
+
+func newOptions(opt ...OptionsFunc) options {
+	opts := options{
+		handler:  defaultHandler,
+		registry: metrics.DefaultRegistry,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	return opts
+}
+
+// UnaryServerInterceptor 捕获handler中的panic并转换为错误，需要紧跟在header拦截器之后
+func UnaryServerInterceptor(opt ...OptionsFunc) grpc.UnaryServerInterceptor {
+	opts := newOptions(opt...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, opts.recover(ctx, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 同UnaryServerInterceptor
+func StreamServerInterceptor(opt ...OptionsFunc) grpc.StreamServerInterceptor {
+	opts := newOptions(opt...)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = opts.recover(stream.Context(), info.FullMethod, p)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func (o options) recover(ctx context.Context, method string, p interface{}) error {
+	ctx = contextWithLogId(ctx)
+	var buf [4096]byte
+	n := runtime.Stack(buf[:], false)
+	log.Errorf(ctx, "panic method:%s err:%+v", method, p)
+	log.Errorf(ctx, "%s", string(buf[:n]))
+
+	metrics.GetOrRegisterCounter(toolbox.StatProtoMetrix(method)+suffixPanic, o.registry).Inc(1)
+	return o.handler(ctx, p)
+}
+
+// contextWithLogId 没有经过header拦截器时，日志中也要带上log-id
+func contextWithLogId(ctx context.Context) context.Context {
+	if _, ok := log.LogIdFromContext(ctx); ok {
+		return ctx
+	}
+	logId := header.LogIdFromIncoming(ctx)
+	if logId == "" {
+		logId = log.GenLogId()
+	}
+	return context.WithValue(ctx, log.LogIDKey, logId)
+}
@@ -0,0 +1,62 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox"
+	"github.com/zer0131/toolbox/interceptor/header"
+	"github.com/zer0131/toolbox/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	resp, err := UnaryServerInterceptor(WithRegistry(r))(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Fatalf("expect Internal actual resp=%v err=%v", resp, err)
+	}
+	if v := r.Get(toolbox.StatProtoMetrix(info.FullMethod) + suffixPanic).(metrics.Counter).Count(); v != 1 {
+		t.Errorf("expect 1 panic actual %d", v)
+	}
+}
+
+func TestStreamServerInterceptorWithHandler(t *testing.T) {
+	custom := errors.New("custom")
+	info := &grpc.StreamServerInfo{FullMethod: "/helloworld.Greeter/SayHelloStream"}
+	interceptor := StreamServerInterceptor(WithRegistry(metrics.NewRegistry()), WithHandler(func(ctx context.Context, p interface{}) error {
+		return custom
+	}))
+	err := interceptor(nil, &fakeServerStream{}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("boom")
+	})
+	if err != custom {
+		t.Fatalf("expect custom err actual %v", err)
+	}
+}
+
+func TestRecoverLogId(t *testing.T) {
+	logs := log.Capture(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(header.RequestIdKey, "req-1"))
+	_, _ = UnaryServerInterceptor(WithRegistry(metrics.NewRegistry()))(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if len(logs.Entries(log.ByLogId("req-1"), log.ByContains("panic method"))) != 1 {
+		t.Errorf("expect panic log with log-id req-1 actual %+v", logs.Entries())
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *fakeServerStream) Context() context.Context { return context.Background() }
@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/zer0131/toolbox/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor 从incoming metadata的traceparent恢复上游的trace，为每个请求创建server span。
+// log-id由header拦截器解析，请求中没有log-id和x-request-id时使用traceparent中的trace id
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, span := startServerSpan(ctx, info.FullMethod)
+		resp, err := handler(newCtx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
+
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, span := startServerSpan(stream.Context(), info.FullMethod)
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		err := handler(srv, wrapped)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor 为每次调用创建client span，并通过traceparent传给下游
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		newCtx, span := startClientSpan(ctx, method, cc.Target())
+		err := invoker(newCtx, method, req, reply, cc, opts...)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor 同UnaryClientInterceptor，span在RecvMsg返回错误(包括io.EOF)时结束，
+// 服务端只返回一个消息的client stream在第一次RecvMsg成功时结束
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		newCtx, span := startClientSpan(ctx, method, cc.Target())
+		cs, err := streamer(newCtx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		return &spanClientStream{ClientStream: cs, span: span, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+func startServerSpan(ctx context.Context, method string) (context.Context, *trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(trace.TraceParentKey); len(v) > 0 {
+		if sc, ok := trace.ParseTraceParent(v[0]); ok {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+
+	newCtx, span := trace.Start(ctx, method, trace.KindServer)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	if p, ok := peer.FromContext(ctx); ok {
+		span.SetAttribute("net.peer.addr", p.Addr.String())
+	}
+	return newCtx, span
+}
+
+func startClientSpan(ctx context.Context, method, target string) (context.Context, *trace.Span) {
+	newCtx, span := trace.Start(ctx, method, trace.KindClient)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.method", method)
+	span.SetAttribute("net.peer.name", target)
+
+	md, ok := metadata.FromOutgoingContext(newCtx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md.Set(trace.TraceParentKey, span.SpanContext().TraceParent())
+	return metadata.NewOutgoingContext(newCtx, md), span
+}
+
+func endSpan(span *trace.Span, err error) {
+	span.SetAttribute("rpc.grpc.status_code", status.Code(err).String())
+	span.SetError(err)
+	span.End()
+}
+
+type spanClientStream struct {
+	grpc.ClientStream
+	span          *trace.Span
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *spanClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	// 服务端不是stream时调用方收到响应后不会再RecvMsg，不能等io.EOF
+	if err != nil || !s.serverStreams {
+		s.once.Do(func() {
+			spanErr := err
+			if spanErr == io.EOF {
+				spanErr = nil
+			}
+			endSpan(s.span, spanErr)
+		})
+	}
+	return err
+}
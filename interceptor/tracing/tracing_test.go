@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zer0131/toolbox/interceptor/header"
+	"github.com/zer0131/toolbox/log"
+	"github.com/zer0131/toolbox/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	trace.RegisterExporter(exporter)
+	defer trace.ResetExporters()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(trace.TraceParentKey, traceParent))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+	chain := func(ctx context.Context, req interface{}) (interface{}, error) {
+		// 没有log-id时header拦截器使用traceparent中的trace id
+		return header.UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if logId, _ := ctx.Value(log.LogIDKey).(string); logId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("expect trace id as log-id actual %s", logId)
+			}
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+	}
+	_, err := UnaryServerInterceptor()(ctx, nil, info, chain)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expect 1 span actual %d", len(spans))
+	}
+	span := spans[0]
+	if span.Kind != trace.KindServer || span.Name != "/svc/Method" || span.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected span %+v", span)
+	}
+	if span.ParentSpanID.String() != "00f067aa0ba902b7" || span.Attributes["rpc.grpc.status_code"] != codes.NotFound.String() {
+		t.Errorf("unexpected span %+v", span)
+	}
+}
+
+func TestUnaryServerInterceptorKeepLogId(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(log.LogIDKey, "123"))
+	_, _ = UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if logId, _ := log.LogIdFromContext(ctx); logId != "123" {
+			t.Errorf("expect log-id 123 actual %s", logId)
+		}
+		return nil, nil
+	})
+}
+
+func TestUnaryServerInterceptorKeepRequestId(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(trace.TraceParentKey, traceParent, header.RequestIdKey, "req-1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}
+	_, _ = UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return header.UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			if logId, _ := ctx.Value(log.LogIDKey).(string); logId != "req-1" {
+				t.Errorf("expect x-request-id as log-id actual %s", logId)
+			}
+			return nil, nil
+		})
+	})
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	trace.RegisterExporter(exporter)
+	defer trace.ResetExporters()
+
+	cc, err := grpc.Dial("127.0.0.1:1", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial err: %s", err)
+	}
+	defer cc.Close()
+
+	ctx, parent := trace.Start(context.Background(), "parent", trace.KindInternal)
+	var sent string
+	_ = UnaryClientInterceptor()(ctx, "/svc/Method", nil, nil, cc, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if v := md.Get(trace.TraceParentKey); len(v) == 1 {
+			sent = v[0]
+		}
+		return nil
+	})
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans actual %d", len(spans))
+	}
+	if sent != spans[0].SpanContext.TraceParent() {
+		t.Errorf("expect traceparent %s actual %s", spans[0].SpanContext.TraceParent(), sent)
+	}
+	if spans[0].ParentSpanID != parent.SpanContext().SpanID {
+		t.Errorf("expect child of parent span")
+	}
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error { return nil }
+
+func TestStreamClientInterceptorClientStream(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	trace.RegisterExporter(exporter)
+	defer trace.ResetExporters()
+
+	cc, err := grpc.Dial("127.0.0.1:1", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dial err: %s", err)
+	}
+	defer cc.Close()
+
+	desc := &grpc.StreamDesc{ClientStreams: true}
+	cs, err := StreamClientInterceptor()(context.Background(), desc, cc, "/svc/ClientStream", func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := cs.RecvMsg(nil); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 || spans[0].Err != nil {
+		t.Fatalf("expect 1 span without err actual %+v", spans)
+	}
+}
@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/zer0131/toolbox/base/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor 先按validate标签校验请求，再调用请求的Validate() error，
+// 失败返回codes.InvalidArgument，detail中的BadRequest列出每个FieldError
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := validateMsg(ctx, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 校验stream中收到的每个消息
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validateStream{ServerStream: stream})
+	}
+}
+
+type validateStream struct {
+	grpc.ServerStream
+}
+
+func (s *validateStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validateMsg(s.Context(), m)
+}
+
+func validateMsg(ctx context.Context, msg interface{}) error {
+	v := reflect.ValueOf(msg)
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+		if br := validate.ValidateStruct(ctx, msg); len(br.FieldErrors) > 0 {
+			return invalidArgument(br.FieldErrors)
+		}
+	}
+
+	if validator, ok := msg.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			// validate包内部返回的是*FieldError，自定义的Validate()两种都可能返回
+			var feP *validate.FieldError
+			if errors.As(err, &feP) && feP != nil {
+				return invalidArgument([]validate.FieldError{*feP})
+			}
+			var fe validate.FieldError
+			if errors.As(err, &fe) {
+				return invalidArgument([]validate.FieldError{fe})
+			}
+			return invalidArgument([]validate.FieldError{{Message: err.Error()}})
+		}
+	}
+	return nil
+}
+
+func invalidArgument(fieldErrors []validate.FieldError) error {
+	br := &errdetails.BadRequest{}
+	for _, fe := range fieldErrors {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       fe.Field,
+			Description: fe.Message,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, fieldErrors[0].Message)
+	if detailed, err := st.WithDetails(br); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
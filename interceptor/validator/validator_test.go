@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zer0131/toolbox/base/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type tagRequest struct {
+	Name string `validate:"min_length(2)"`
+	Age  int32  `validate:"range(0, 150)"`
+}
+
+type customRequest struct {
+	err error
+}
+
+func (r *customRequest) Validate() error {
+	return r.err
+}
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+
+func okHandler(ctx context.Context, req interface{}) (interface{}, error) {
+	return "ok", nil
+}
+
+func badRequest(t *testing.T, err error) *errdetails.BadRequest {
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expect InvalidArgument actual %v", err)
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("expect 1 detail actual %d", len(st.Details()))
+	}
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	if !ok {
+		t.Fatalf("expect BadRequest actual %T", st.Details()[0])
+	}
+	return br
+}
+
+func TestValidateTags(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	if _, err := interceptor(context.Background(), &tagRequest{Name: "tom", Age: 20}, info, okHandler); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	_, err := interceptor(context.Background(), &tagRequest{Name: "t", Age: 200}, info, okHandler)
+	br := badRequest(t, err)
+	if len(br.FieldViolations) != 2 || br.FieldViolations[0].Field != "name" || br.FieldViolations[1].Field != "age" {
+		t.Errorf("unexpected violations %+v", br.FieldViolations)
+	}
+}
+
+func TestValidateMethod(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+
+	_, err := interceptor(context.Background(), &customRequest{err: validate.FieldError{Field: "id", RuleName: "required", Message: "id is required"}}, info, okHandler)
+	br := badRequest(t, err)
+	if len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "id" {
+		t.Errorf("unexpected violations %+v", br.FieldViolations)
+	}
+
+	// 返回*FieldError，包括被包装过的
+	for _, fe := range []error{
+		&validate.FieldError{Field: "name", RuleName: "required", Message: "name is required"},
+		fmt.Errorf("check request: %w", &validate.FieldError{Field: "name", RuleName: "required", Message: "name is required"}),
+	} {
+		_, err = interceptor(context.Background(), &customRequest{err: fe}, info, okHandler)
+		br = badRequest(t, err)
+		if len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "name" || br.FieldViolations[0].Description != "name is required" {
+			t.Errorf("unexpected violations %+v", br.FieldViolations)
+		}
+	}
+
+	_, err = interceptor(context.Background(), &customRequest{err: errors.New("bad")}, info, okHandler)
+	if br := badRequest(t, err); br.FieldViolations[0].Description != "bad" {
+		t.Errorf("unexpected violations %+v", br.FieldViolations)
+	}
+}
@@ -15,9 +15,10 @@ import (
 // 1. 人财物中心，所有接口只允许white_list限制内的ip访问
 // 2. sds，单接口只允许内网访问，所以包含于上面的功能之内
 
-// 用户配置的white_list中的元素有两种形态
+// 用户配置的white_list中的元素有三种形态，ipv4与ipv6均可
 // 1. 10.189.240.0-10.189.240.255 ip段
 // 2. 10.189.241.35 具体ip
+// 3. 10.189.240.0/24 CIDR
 type ipRestrict struct {
 	// 标记是否是ip段
 	isSegment bool
@@ -27,15 +28,22 @@ type ipRestrict struct {
 
 	// 具体ip
 	specfic string
+
+	// CIDR
+	ipNet *net.IPNet
 }
 
 func (r *ipRestrict) hit(ctx context.Context, ipTarget string) bool {
+	target := net.ParseIP(ipTarget)
+	if r.ipNet != nil {
+		return r.ipNet.Contains(target)
+	}
 	if r.isSegment {
-		if bytes.Compare(net.ParseIP(ipTarget), net.ParseIP(r.start)) >= 0 && bytes.Compare(net.ParseIP(ipTarget), net.ParseIP(r.stop)) <= 0 {
+		if bytes.Compare(target, net.ParseIP(r.start)) >= 0 && bytes.Compare(target, net.ParseIP(r.stop)) <= 0 {
 			return true
 		}
 	} else {
-		if r.specfic == ipTarget {
+		if net.ParseIP(r.specfic).Equal(target) {
 			return true
 		}
 	}
@@ -48,6 +56,17 @@ var (
 
 func Init(ctx context.Context, whiteListStrs []string) {
 	for _, whiteListStr := range whiteListStrs {
+		if strings.Contains(whiteListStr, "/") {
+			_, ipNet, err := net.ParseCIDR(whiteListStr)
+			if err != nil {
+				log.Errorf(ctx, "Illegal ip config %s", whiteListStr)
+				continue
+			}
+
+			whiteList = append(whiteList, &ipRestrict{ipNet: ipNet})
+			continue
+		}
+
 		ips := strings.Split(whiteListStr, "-")
 		if len(ips) == 1 {
 			if !validIp(ctx, ips[0]) {
@@ -67,19 +86,37 @@ func Init(ctx context.Context, whiteListStrs []string) {
 	}
 }
 
+// Reset 清空白名单，清空后CheckIp对所有ip都返回true
+func Reset() {
+	whiteList = nil
+}
+
+// Enabled 是否配置了白名单，没有配置时CheckIp对所有ip都返回true
+func Enabled() bool {
+	return len(whiteList) > 0
+}
+
 func validIp(ctx context.Context, ipStr string) bool {
-	if pip := net.ParseIP(ipStr); pip != nil && pip.To4() != nil {
-		return true
+	return net.ParseIP(ipStr) != nil
+}
+
+// SplitHost 去掉地址中的端口，支持10.188.0.24:80、[::1]:80以及不带端口的ip
+func SplitHost(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
 	}
-	return false
+	return strings.Trim(addr, "[]")
 }
 
-//检查IP是否合法
+//检查IP是否合法，ipStr可以带端口
 func CheckIp(ctx context.Context, ipStr string) bool {
 	if whiteList == nil || len(whiteList) == 0 {
 		return true
 	}
 
+	ipStr = SplitHost(ipStr)
+
 	if !validIp(ctx, ipStr) {
 		log.Warnf(ctx, "Invalid ip %s", ipStr)
 		return true
@@ -99,8 +136,8 @@ func GetClientIp(ctx context.Context, r *http.Request) (clientIp string) {
 	}
 
 	defer func() {
-		if clientIp != "" && strings.Contains(clientIp, ":") {
-			clientIp = clientIp[:strings.Index(clientIp, ":")]
+		if clientIp != "" {
+			clientIp = SplitHost(clientIp)
 		}
 	}()
 
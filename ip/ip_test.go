@@ -2,6 +2,7 @@ package ip
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"reflect"
 	"testing"
@@ -33,6 +34,14 @@ func Test_validIp(t *testing.T) {
 			ipStr:        "127.0.0.1",
 			expectResult: true,
 		},
+		{
+			ipStr:        "::1",
+			expectResult: true,
+		},
+		{
+			ipStr:        "[::1]:8080",
+			expectResult: false,
+		},
 	}
 
 	for i, tt := range tests {
@@ -121,6 +130,28 @@ func Test_ipRestrict_hit(t *testing.T) {
 			},
 			expectResult: false,
 		},
+		{
+			ipTarget: "::1",
+			rt: &ipRestrict{
+				isSegment: false,
+				specfic:   "0:0::1",
+			},
+			expectResult: true,
+		},
+		{
+			ipTarget: "fd00::10",
+			rt: &ipRestrict{
+				ipNet: mustParseCIDR("fd00::/64"),
+			},
+			expectResult: true,
+		},
+		{
+			ipTarget: "10.189.0.24",
+			rt: &ipRestrict{
+				ipNet: mustParseCIDR("10.188.0.0/16"),
+			},
+			expectResult: false,
+		},
 	}
 
 	for idx, tt := range tests {
@@ -158,3 +189,48 @@ func Test_GetClientIp(t *testing.T) {
 		t.SkipNow()
 	}
 }
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+func Test_SplitHost(t *testing.T) {
+	var tests = []struct {
+		addr   string
+		expect string
+	}{
+		{addr: "10.188.0.24:8080", expect: "10.188.0.24"},
+		{addr: "10.188.0.24", expect: "10.188.0.24"},
+		{addr: "[::1]:8080", expect: "::1"},
+		{addr: "::1", expect: "::1"},
+		{addr: "[fd00::1]", expect: "fd00::1"},
+	}
+
+	for idx, tt := range tests {
+		if actual := SplitHost(tt.addr); actual != tt.expect {
+			t.Errorf("Index %d expect %s actual %s", idx, tt.expect, actual)
+		}
+	}
+}
+
+func Test_CheckIp(t *testing.T) {
+	whiteList = make([]*ipRestrict, 0)
+	defer func() {
+		whiteList = nil
+	}()
+
+	Init(context.Background(), []string{"10.188.0.0/16", "::1"})
+	if !CheckIp(context.Background(), "10.188.0.24:12345") {
+		t.Error("10.188.0.24:12345 should pass")
+	}
+	if !CheckIp(context.Background(), "[::1]:12345") {
+		t.Error("[::1]:12345 should pass")
+	}
+	if CheckIp(context.Background(), "10.189.0.24:12345") {
+		t.Error("10.189.0.24:12345 should not pass")
+	}
+}
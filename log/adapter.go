@@ -0,0 +1,208 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"os"
+	"strings"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+	"google.golang.org/grpc/grpclog"
+)
+
+// 第三方库的logger适配到全局logger，没有ctx的日志不带log-id
+
+// levelEnabled level是否达到全局logger的level
+func levelEnabled(level string) bool {
+	return levelIndex(level) >= levelIndex(GetLogLevel())
+}
+
+// NewStdLogger 标准库的*log.Logger，每次输出作为一条level的日志，
+// 例如redis.SetLogger(log.NewStdLogger(log.LevelInfo, "redis: "))
+func NewStdLogger(level, prefix string) *stdlog.Logger {
+	return stdlog.New(&levelWriter{level: level}, prefix, 0)
+}
+
+type levelWriter struct {
+	level string
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	logrus_wrap.Log(w.level, nil, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// PrintLogger 实现Print、Printf、Println，用于只需要这几个方法的logger，
+// 例如mysql.SetLogger(log.NewPrintLogger(log.LevelError, "[mysql] "))、elastic.SetErrorLog
+type PrintLogger struct {
+	level  string
+	prefix string
+}
+
+func NewPrintLogger(level, prefix string) *PrintLogger {
+	return &PrintLogger{level: level, prefix: prefix}
+}
+
+func (l *PrintLogger) Print(v ...interface{}) {
+	logrus_wrap.Log(l.level, nil, l.prefix+fmt.Sprint(v...))
+}
+
+func (l *PrintLogger) Printf(format string, v ...interface{}) {
+	logrus_wrap.Logf(l.level, nil, l.prefix+format, v...)
+}
+
+func (l *PrintLogger) Println(v ...interface{}) {
+	logrus_wrap.Log(l.level, nil, l.prefix+strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// NewGrpcLogger grpclog.LoggerV2，低于level的日志不输出（grpc默认只输出ERROR），
+// verbosity对应GRPC_GO_LOG_VERBOSITY_LEVEL，例如grpclog.SetLoggerV2(log.NewGrpcLogger(log.LevelWarn, 0))
+func NewGrpcLogger(level string, verbosity int) grpclog.LoggerV2 {
+	return &grpcLogger{level: level, verbosity: verbosity}
+}
+
+type grpcLogger struct {
+	level     string
+	verbosity int
+}
+
+func (l *grpcLogger) log(level string, msg string) {
+	if levelIndex(level) >= levelIndex(l.level) {
+		logrus_wrap.Log(level, nil, "[grpc] "+msg)
+	}
+}
+
+func (l *grpcLogger) Info(args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Infoln(args ...interface{}) {
+	l.log(LevelInfo, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *grpcLogger) Warning(args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Warningln(args ...interface{}) {
+	l.log(LevelWarn, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Warningf(format string, args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *grpcLogger) Error(args ...interface{}) {
+	l.log(LevelError, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Errorln(args ...interface{}) {
+	l.log(LevelError, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatal grpc要求Fatal之后退出，退出前写完缓冲中的日志
+func (l *grpcLogger) Fatal(args ...interface{}) {
+	l.fatal(fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Fatalln(args ...interface{}) {
+	l.fatal(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Fatalf(format string, args ...interface{}) {
+	l.fatal(fmt.Sprintf(format, args...))
+}
+
+func (l *grpcLogger) fatal(msg string) {
+	logrus_wrap.Log(LevelError, nil, "[grpc] "+msg)
+	Close()
+	os.Exit(1)
+}
+
+func (l *grpcLogger) V(v int) bool {
+	return v <= l.verbosity
+}
+
+// LogrLogger 与github.com/go-logr/logr的Logger方法一致，V越大越详细：V(0)为INFO，V(1)及以上为DEBUG，
+// Error不受V影响。kv为key、value交替，与InfoKV相同
+type LogrLogger interface {
+	Enabled() bool
+	Info(msg string, kv ...interface{})
+	Error(err error, msg string, kv ...interface{})
+	V(level int) LogrLogger
+	WithValues(kv ...interface{}) LogrLogger
+	WithName(name string) LogrLogger
+}
+
+// NewLogr ctx中的log-id等字段会输出到每一条日志
+func NewLogr(ctx context.Context) LogrLogger {
+	return &logrLogger{ctx: ctx}
+}
+
+type logrLogger struct {
+	ctx    context.Context
+	name   string
+	values []interface{}
+	v      int
+}
+
+func (l *logrLogger) level() string {
+	if l.v > 0 {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+func (l *logrLogger) fields(kv []interface{}) logrus_wrap.Fields {
+	fields := kvFields(l.ctx, append(l.values[:len(l.values):len(l.values)], kv...))
+	if l.name != "" {
+		fields["logger"] = l.name
+	}
+	return fields
+}
+
+func (l *logrLogger) Enabled() bool {
+	return levelEnabled(l.level())
+}
+
+func (l *logrLogger) Info(msg string, kv ...interface{}) {
+	logrus_wrap.Log(l.level(), l.fields(kv), msg)
+}
+
+func (l *logrLogger) Error(err error, msg string, kv ...interface{}) {
+	fields := l.fields(kv)
+	fields["error"] = err
+	logrus_wrap.Log(LevelError, fields, msg)
+}
+
+func (l *logrLogger) V(level int) LogrLogger {
+	n := *l
+	n.v += level
+	return &n
+}
+
+func (l *logrLogger) WithValues(kv ...interface{}) LogrLogger {
+	n := *l
+	n.values = append(l.values[:len(l.values):len(l.values)], kv...)
+	return &n
+}
+
+// WithName 多次调用时用.连接
+func (l *logrLogger) WithName(name string) LogrLogger {
+	n := *l
+	if n.name != "" {
+		n.name += "." + name
+	} else {
+		n.name = name
+	}
+	return &n
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLogrLogger(t *testing.T) {
+	ctx := NewContextWithSpecifyLogID(context.Background(), "logr-id")
+	l := NewLogr(ctx).WithName("db").WithValues("table", "user").WithName("query")
+	logr := l.(*logrLogger)
+	fields := logr.fields([]interface{}{"rows", 3})
+	if fields["logger"] != "db.query" || fields["table"] != "user" || fields["rows"] != 3 || fields[LogIDKey] != "logr-id" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	// WithValues不修改原来的logger
+	l.WithValues("extra", 1)
+	if len(logr.values) != 2 {
+		t.Errorf("unexpected values %v", logr.values)
+	}
+	if logr.level() != LevelInfo || l.V(1).(*logrLogger).level() != LevelDebug {
+		t.Errorf("unexpected level")
+	}
+
+	logs := Capture(t)
+	l.Info("query ok", "cost", 1)
+	l.V(1).Info("debug detail")
+	l.Error(errors.New("timeout"), "query failed")
+	if e := logs.Entries(ByLogId("logr-id")); len(e) != 3 || e[1].Level != LevelDebug || e[2].Fields["error"].(error).Error() != "timeout" {
+		t.Errorf("unexpected entries %+v", e)
+	}
+}
+
+func TestGrpcLogger(t *testing.T) {
+	logs := Capture(t)
+	l := NewGrpcLogger(LevelWarn, 2)
+	if !l.V(2) || l.V(3) {
+		t.Errorf("unexpected verbosity")
+	}
+	l.Info("not printed")
+	l.Warningf("grpc %s", "warn")
+	l.Errorln("grpc", "error")
+	if e := logs.Entries(); len(e) != 2 || e[0].Message != "[grpc] grpc warn" || e[1].Message != "[grpc] grpc error" {
+		t.Errorf("unexpected entries %+v", e)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	logs := Capture(t)
+	NewStdLogger(LevelInfo, "redis: ").Printf("pool %s", "closed")
+	p := NewPrintLogger(LevelError, "[mysql] ")
+	p.Print("invalid connection")
+	p.Printf("packets.go:%d: %s", 36, "unexpected EOF")
+	want := []string{"redis: pool closed", "[mysql] invalid connection", "[mysql] packets.go:36: unexpected EOF"}
+	e := logs.Entries()
+	if len(e) != len(want) {
+		t.Fatalf("unexpected entries %+v", e)
+	}
+	for i := range want {
+		if e[i].Message != want[i] {
+			t.Errorf("unexpected message %s, want %s", e[i].Message, want[i])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+)
+
+// Entry 捕获的一条日志
+type Entry = logrus_wrap.Entry
+
+// CaptureT testing.T、testing.B都满足这个接口，log包不依赖testing
+type CaptureT interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...interface{})
+}
+
+// Captured Capture返回的handle
+type Captured struct {
+	capture *logrus_wrap.Capture
+}
+
+// Capture 测试中把全局logger替换为只输出到内存的logger，t.Cleanup时恢复之前的logger，
+// opt中WithSinks以外的配置同样生效，例如WithLogLevel、WithRedact。
+// 替换的是全局logger，不能与t.Parallel一起使用，例如：
+//
+//	logs := log.Capture(t)
+//	doSomething(ctx)
+//	if len(logs.Entries(log.ByLevel(log.LevelError), log.ByContains("timeout"))) == 0 { ... }
+func Capture(t CaptureT, opt ...LogOptionsFunc) *Captured {
+	t.Helper()
+	opts := defaultLogOptions
+	for _, o := range opt {
+		o(&opts)
+	}
+	capture, restore, err := logrus_wrap.NewCapture(opts.wrapOptions()...)
+	if err != nil {
+		t.Fatalf("capture log err: %s", err)
+		return nil
+	}
+	t.Cleanup(restore)
+	return &Captured{capture: capture}
+}
+
+// EntryFilter Entries的过滤条件
+type EntryFilter func(Entry) bool
+
+// ByLevel 只返回level的日志
+func ByLevel(level string) EntryFilter {
+	return func(e Entry) bool {
+		return e.Level == level
+	}
+}
+
+func ByLogId(logId string) EntryFilter {
+	return func(e Entry) bool {
+		return e.LogId == logId
+	}
+}
+
+// ByContains 消息中包含substr
+func ByContains(substr string) EntryFilter {
+	return func(e Entry) bool {
+		return strings.Contains(e.Message, substr)
+	}
+}
+
+// Entries 按输出顺序返回同时满足所有filter的日志
+func (c *Captured) Entries(filters ...EntryFilter) []Entry {
+	var entries []Entry
+	for _, e := range c.capture.Entries() {
+		matched := true
+		for _, f := range filters {
+			if !f(e) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Reset 清空已捕获的日志
+func (c *Captured) Reset() {
+	c.capture.Reset()
+}
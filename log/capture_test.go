@@ -0,0 +1,53 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "log")
+	var outer *Captured
+	t.Run("capture", func(t *testing.T) {
+		logs := Capture(t, WithPath(dir), WithLogLevel(LevelInfo))
+		outer = logs
+		ctx := NewContextWithSpecifyLogID(context.Background(), "capture-id")
+		Debugf(ctx, "debug %d", 1)
+		Infof(ctx, "user %d login", 42)
+		Errorf(context.Background(), "query timeout after %dms", 100)
+		WarnKV(ctx, "slow query", "cost", 300)
+
+		if n := len(logs.Entries()); n != 3 {
+			t.Errorf("unexpected entries %d: %+v", n, logs.Entries())
+		}
+		if e := logs.Entries(ByLevel(LevelError)); len(e) != 1 || e[0].Message != "query timeout after 100ms" {
+			t.Errorf("unexpected error entries %+v", e)
+		}
+		if e := logs.Entries(ByLogId("capture-id"), ByContains("slow")); len(e) != 1 || e[0].Fields["cost"] != 300 || e[0].Level != LevelWarn {
+			t.Errorf("unexpected entries %+v", e)
+		}
+		logs.Reset()
+		if len(logs.Entries()) != 0 {
+			t.Errorf("entries not reset")
+		}
+	})
+
+	// Cleanup之后恢复之前的logger
+	Infof(context.Background(), "after capture")
+	if len(outer.Entries()) != 0 {
+		t.Errorf("captured after cleanup %+v", outer.Entries())
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("log dir created")
+	}
+}
+
+func TestCaptureRedact(t *testing.T) {
+	logs := Capture(t, WithRedact())
+	Infof(context.Background(), "send sms to %s", "13800138000")
+	if e := logs.Entries(ByContains("138****8000")); len(e) != 1 {
+		t.Errorf("unexpected entries %+v", logs.Entries())
+	}
+}
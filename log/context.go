@@ -0,0 +1,51 @@
+package log
+
+import (
+	"context"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+)
+
+type fieldsCtxKey struct{}
+
+// WithFields 在ctx上附加字段，之后使用这个ctx打印的日志都会带上，例如：
+// ctx = log.WithFields(ctx, "user_id", uid)
+// kv的格式与InfoKV相同，同名的key后设置的覆盖先设置的
+func WithFields(ctx context.Context, kv ...interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := FieldsFromContext(ctx)
+	fields := make(logrus_wrap.Fields, len(parent)+len(kv)/2)
+	for k, v := range parent {
+		fields[k] = v
+	}
+	appendKV(fields, kv)
+	return context.WithValue(ctx, fieldsCtxKey{}, fields)
+}
+
+// FieldsFromContext WithFields附加的字段，返回值不能修改
+func FieldsFromContext(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsCtxKey{}).(logrus_wrap.Fields)
+	return fields
+}
+
+// contextFields 每条日志都会带上的字段：log-id、remote-addr以及WithFields附加的字段，
+// extra为本次调用的字段
+func contextFields(ctx context.Context, extra int) logrus_wrap.Fields {
+	custom := FieldsFromContext(ctx)
+	fields := make(logrus_wrap.Fields, len(custom)+extra+2)
+	for k, v := range custom {
+		fields[k] = v
+	}
+	if logId, ok := LogIdFromContext(ctx); ok {
+		fields[logrus_wrap.KeyLogId] = logId
+	}
+	if remoteAddr := RemoteAddrNameFromContext(ctx); remoteAddr != "" {
+		fields[logrus_wrap.KeyRemoteAddr] = remoteAddr
+	}
+	return fields
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestContextFields(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithProject("ctx"), WithPath(dir))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+
+	// ctx value中的log-id
+	ctx := context.WithValue(context.Background(), LogIDKey, "value-id")
+	ctx = WithFields(ctx, "user_id", 1)
+	ctx = WithFields(ctx, "user_id", 2, "op", "login")
+	logObj.Infof(ctx, "hello %s", "world")
+
+	// incoming metadata优先
+	mdCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(LogIDKey, "md-id", RemoteAddrName, "1.1.1.1:80"))
+	logObj.Warn(mdCtx, "warn")
+
+	// nil ctx不会panic
+	logObj.Error(nil, "nil ctx")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "ctx.log"))
+	if err != nil {
+		t.Fatalf("read log err: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 lines actual %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[0], "[value-id] hello world op=login user_id=2") {
+		t.Errorf("unexpected line %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "[md-id] remote-addr=1.1.1.1:80 warn op=login user_id=2") {
+		t.Errorf("unexpected line %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], " nil ctx") {
+		t.Errorf("unexpected line %s", lines[2])
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+)
+
+// badKey kv个数为奇数时，最后一个value使用的key
+const badKey = "!BADKEY"
+
+// DebugKV 结构化日志，kv为key、value交替，例如：
+// log.InfoKV(ctx, "user login", "user_id", uid, "cost", cost)
+// 使用WithFormat(FormatJSON)时字段输出为独立的key，text格式下追加为k=v
+func DebugKV(ctx context.Context, msg string, kv ...interface{}) {
+	logrus_wrap.Log(logrus_wrap.LevelDebug, kvFields(ctx, kv), msg)
+}
+
+func InfoKV(ctx context.Context, msg string, kv ...interface{}) {
+	logrus_wrap.Log(logrus_wrap.LevelInfo, kvFields(ctx, kv), msg)
+}
+
+func WarnKV(ctx context.Context, msg string, kv ...interface{}) {
+	logrus_wrap.Log(logrus_wrap.LevelWarn, kvFields(ctx, kv), msg)
+}
+
+func ErrorKV(ctx context.Context, msg string, kv ...interface{}) {
+	logrus_wrap.Log(logrus_wrap.LevelError, kvFields(ctx, kv), msg)
+}
+
+func (logObj *Logger) DebugKV(ctx context.Context, msg string, kv ...interface{}) {
+	logObj.logger.Log(logrus_wrap.LevelDebug, kvFields(ctx, kv), msg)
+}
+
+func (logObj *Logger) InfoKV(ctx context.Context, msg string, kv ...interface{}) {
+	logObj.logger.Log(logrus_wrap.LevelInfo, kvFields(ctx, kv), msg)
+}
+
+func (logObj *Logger) WarnKV(ctx context.Context, msg string, kv ...interface{}) {
+	logObj.logger.Log(logrus_wrap.LevelWarn, kvFields(ctx, kv), msg)
+}
+
+func (logObj *Logger) ErrorKV(ctx context.Context, msg string, kv ...interface{}) {
+	logObj.logger.Log(logrus_wrap.LevelError, kvFields(ctx, kv), msg)
+}
+
+func kvFields(ctx context.Context, kv []interface{}) logrus_wrap.Fields {
+	fields := contextFields(ctx, len(kv)/2)
+	appendKV(fields, kv)
+	return fields
+}
+
+func appendKV(fields logrus_wrap.Fields, kv []interface{}) {
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			fields[badKey] = kv[i]
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields[key] = kv[i+1]
+	}
+}
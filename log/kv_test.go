@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInfoKVJSON(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithProject("kv"), WithPath(dir), WithFormat(FormatJSON))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	ctx := NewContextWithSpecifyLogID(context.Background(), "123")
+	logObj.InfoKV(ctx, "user login", "user_id", 42, "err", errors.New("failed"), "msg", "conflict")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "kv.log"))
+	if err != nil {
+		t.Fatalf("read log err: %s", err)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(b, &line); err != nil {
+		t.Fatalf("unmarshal %s err: %s", b, err)
+	}
+	expect := map[string]interface{}{
+		"level":      "INFO",
+		"log-id":     "123",
+		"msg":        "user login",
+		"user_id":    float64(42),
+		"err":        "failed",
+		"fields.msg": "conflict",
+	}
+	for k, v := range expect {
+		if line[k] != v {
+			t.Errorf("key %s expect %v actual %v", k, v, line[k])
+		}
+	}
+	// log包内的调用栈会被跳过，这里只检查caller存在
+	if caller, _ := line["caller"].(string); caller == "" {
+		t.Errorf("expect caller")
+	}
+}
+
+func TestInfoKVText(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithProject("kv"), WithPath(dir))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	ctx := NewContextWithSpecifyLogID(context.Background(), "123")
+	logObj.InfoKV(ctx, "user login", "user_id", 42, "name", "a b", "odd")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "kv.log"))
+	if err != nil {
+		t.Fatalf("read log err: %s", err)
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasSuffix(line, `[123] user login !BADKEY=odd name="a b" user_id=42`) {
+		t.Errorf("unexpected line %s", line)
+	}
+}
@@ -0,0 +1,272 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+)
+
+// levels 从详细到简略，信号按这个顺序调整
+var levels = []string{LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+func isValidLevel(level string) bool {
+	return levelIndex(level) >= 0
+}
+
+// levelIndex level在levels中的位置，不存在时返回-1
+func levelIndex(level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// stepLevel 在levels中移动step，超出范围时停在两端
+func stepLevel(level string, step int) string {
+	i := levelIndex(level)
+	if i < 0 {
+		return LevelInfo
+	}
+	i += step
+	if i < 0 {
+		i = 0
+	} else if i >= len(levels) {
+		i = len(levels) - 1
+	}
+	return levels[i]
+}
+
+// levelReverter 记录SetLogLevelFor的修改，到期后恢复为修改之前的level
+type levelReverter struct {
+	mutex       sync.Mutex
+	generation  uint64
+	timer       *time.Timer
+	revertLevel string
+	revertAt    time.Time
+}
+
+// set d>0时在d之后恢复，连续的临时修改恢复到第一次修改之前的level；d<=0时取消还未恢复的修改
+func (r *levelReverter) set(level string, d time.Duration, get func() string, set func(string)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	prev := get()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+		prev = r.revertLevel
+		r.revertLevel = ""
+	}
+	r.generation++
+	set(level)
+	if d <= 0 {
+		return
+	}
+
+	gen := r.generation
+	r.revertLevel = prev
+	r.revertAt = time.Now().Add(d)
+	r.timer = time.AfterFunc(d, func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if r.generation != gen {
+			return
+		}
+		set(r.revertLevel)
+		r.timer = nil
+		r.revertLevel = ""
+	})
+}
+
+// pending 还未恢复的临时修改
+func (r *levelReverter) pending() (string, time.Time, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.revertLevel, r.revertAt, r.timer != nil
+}
+
+// stop 取消还未恢复的修改，不修改当前level
+func (r *levelReverter) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+		r.revertLevel = ""
+	}
+	r.generation++
+}
+
+var globalLevel levelReverter
+
+// GetLogLevel 全局logger的level
+func GetLogLevel() string {
+	return logrus_wrap.GetLevel()
+}
+
+// SetLogLevelFor 临时修改全局logger的level，d之后自动恢复，例如SetLogLevelFor(LevelDebug, 10*time.Minute)
+func SetLogLevelFor(level string, d time.Duration) {
+	globalLevel.set(level, d, logrus_wrap.GetLevel, logrus_wrap.SetLevel)
+}
+
+// SetLogLevel 修改level，会取消SetLogLevelFor还未恢复的修改
+func (logObj *Logger) SetLogLevel(level string) {
+	logObj.level.set(level, 0, logObj.logger.GetLevel, logObj.logger.SetLevel)
+}
+
+func (logObj *Logger) SetLogLevelFor(level string, d time.Duration) {
+	logObj.level.set(level, d, logObj.logger.GetLevel, logObj.logger.SetLevel)
+}
+
+func (logObj *Logger) GetLogLevel() string {
+	return logObj.logger.GetLevel()
+}
+
+var (
+	namedMutex   sync.RWMutex
+	namedLoggers = make(map[string]*Logger)
+)
+
+func registerLogger(logger *Logger) {
+	namedMutex.Lock()
+	defer namedMutex.Unlock()
+	namedLoggers[logger.name] = logger
+}
+
+func unregisterLogger(logger *Logger) {
+	if logger.name == "" {
+		return
+	}
+	namedMutex.Lock()
+	defer namedMutex.Unlock()
+	if namedLoggers[logger.name] == logger {
+		delete(namedLoggers, logger.name)
+	}
+}
+
+// NamedLogger 通过WithName注册的Logger，没有时返回nil
+func NamedLogger(name string) *Logger {
+	namedMutex.RLock()
+	defer namedMutex.RUnlock()
+	return namedLoggers[name]
+}
+
+// LevelState LevelHandler的返回，Name为空表示全局logger
+type LevelState struct {
+	Name        string `json:"name"`
+	Level       string `json:"level"`
+	RevertLevel string `json:"revert_level,omitempty"`
+	RevertAt    string `json:"revert_at,omitempty"`
+}
+
+type levelRequest struct {
+	Name     string `json:"name"`
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+// LevelHandler 查看和修改level：
+//
+//	GET                       返回全局logger和所有命名Logger的level
+//	GET ?name=xxx             返回一个Logger的level，name为空表示全局logger
+//	PUT {"name":"xxx","level":"DEBUG","duration":"10m"}
+//	                          修改level，duration不为空时到期自动恢复
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if names, ok := r.URL.Query()["name"]; ok {
+				state, ok := levelState(names[0])
+				if !ok {
+					http.Error(w, fmt.Sprintf("logger %s not found", names[0]), http.StatusNotFound)
+					return
+				}
+				writeJSON(w, state)
+				return
+			}
+			writeJSON(w, levelStates())
+		case http.MethodPut:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid body: %s", err), http.StatusBadRequest)
+				return
+			}
+			if !isValidLevel(req.Level) {
+				http.Error(w, fmt.Sprintf("unknown level %s", req.Level), http.StatusBadRequest)
+				return
+			}
+			var d time.Duration
+			if req.Duration != "" {
+				var err error
+				if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+					http.Error(w, fmt.Sprintf("invalid duration %s", req.Duration), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Name == "" {
+				SetLogLevelFor(req.Level, d)
+			} else if logger := NamedLogger(req.Name); logger != nil {
+				logger.SetLogLevelFor(req.Level, d)
+			} else {
+				http.Error(w, fmt.Sprintf("logger %s not found", req.Name), http.StatusNotFound)
+				return
+			}
+			state, _ := levelState(req.Name)
+			writeJSON(w, state)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func levelState(name string) (LevelState, bool) {
+	state := LevelState{Name: name}
+	reverter := &globalLevel
+	if name == "" {
+		state.Level = GetLogLevel()
+	} else {
+		logger := NamedLogger(name)
+		if logger == nil {
+			return state, false
+		}
+		state.Level = logger.GetLogLevel()
+		reverter = &logger.level
+	}
+	if level, at, ok := reverter.pending(); ok {
+		state.RevertLevel = level
+		state.RevertAt = at.Format(time.RFC3339)
+	}
+	return state, true
+}
+
+func levelStates() []LevelState {
+	namedMutex.RLock()
+	names := make([]string, 0, len(namedLoggers))
+	for name := range namedLoggers {
+		names = append(names, name)
+	}
+	namedMutex.RUnlock()
+	sort.Strings(names)
+
+	global, _ := levelState("")
+	states := []LevelState{global}
+	for _, name := range names {
+		if state, ok := levelState(name); ok {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
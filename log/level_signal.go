@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleLevelSignals 收到SIGUSR1时全局logger的level调详细一级（例如INFO到DEBUG），
+// 收到SIGUSR2时调简略一级，返回的函数用于停止处理
+func HandleLevelSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				if sig == syscall.SIGUSR1 {
+					SetLogLevel(stepLevel(GetLogLevel(), -1))
+				} else {
+					SetLogLevel(stepLevel(GetLogLevel(), 1))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package log
+
+// HandleLevelSignals windows没有SIGUSR1、SIGUSR2，不做处理
+func HandleLevelSignals() (stop func()) {
+	return func() {}
+}
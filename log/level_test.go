@@ -0,0 +1,106 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelReverter(t *testing.T) {
+	logObj, err := NewCustomLogger(WithProject("level"), WithPath(t.TempDir()), WithLogLevel(LevelWarn))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	defer logObj.Close()
+
+	logObj.SetLogLevelFor(LevelDebug, 50*time.Millisecond)
+	// 连续的临时修改恢复到第一次修改之前的level
+	logObj.SetLogLevelFor(LevelInfo, 50*time.Millisecond)
+	if l := logObj.GetLogLevel(); l != LevelInfo {
+		t.Errorf("unexpected level %s", l)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if l := logObj.GetLogLevel(); l != LevelWarn {
+		t.Errorf("level not reverted %s", l)
+	}
+
+	// 直接修改取消还未恢复的修改
+	logObj.SetLogLevelFor(LevelDebug, 50*time.Millisecond)
+	logObj.SetLogLevel(LevelError)
+	time.Sleep(100 * time.Millisecond)
+	if l := logObj.GetLogLevel(); l != LevelError {
+		t.Errorf("unexpected level %s", l)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	logObj, err := NewCustomLogger(WithProject("named"), WithPath(t.TempDir()), WithLogLevel(LevelInfo), WithName("named"))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	handler := LevelHandler()
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/", "")
+	var states []LevelState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatalf("unmarshal err: %s, body: %s", err, rec.Body)
+	}
+	if len(states) != 2 || states[0].Name != "" || states[1] != (LevelState{Name: "named", Level: LevelInfo}) {
+		t.Errorf("unexpected states %+v", states)
+	}
+
+	rec = do(http.MethodPut, "/", `{"name":"named","level":"DEBUG","duration":"10m"}`)
+	var state LevelState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("unmarshal err: %s, body: %s", err, rec.Body)
+	}
+	if state.Level != LevelDebug || state.RevertLevel != LevelInfo || state.RevertAt == "" {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if l := logObj.GetLogLevel(); l != LevelDebug {
+		t.Errorf("unexpected level %s", l)
+	}
+
+	if rec := do(http.MethodPut, "/", `{"name":"named","level":"TRACE"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected code %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/", `{"name":"named","level":"INFO","duration":"abc"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected code %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected code %d", rec.Code)
+	}
+
+	// Close之后不再注册
+	_ = logObj.Close()
+	if rec := do(http.MethodGet, "/?name=named", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected code %d", rec.Code)
+	}
+}
+
+func TestStepLevel(t *testing.T) {
+	cases := []struct {
+		level string
+		step  int
+		want  string
+	}{
+		{LevelInfo, -1, LevelDebug},
+		{LevelDebug, -1, LevelDebug},
+		{LevelWarn, 1, LevelError},
+		{LevelError, 1, LevelError},
+		{"unknown", 1, LevelInfo},
+	}
+	for _, c := range cases {
+		if got := stepLevel(c.level, c.step); got != c.want {
+			t.Errorf("stepLevel(%s, %d) = %s, want %s", c.level, c.step, got, c.want)
+		}
+	}
+}
@@ -15,11 +15,32 @@ const (
 	RemoteAddrName                = "remote-addr"
 	DefaultFileWriterMaxBackupDay = 7
 	DefaultFileWriterMaxLength    = 8192
+	DefaultAsyncBufferSize        = 8192
+)
+
+const (
+	LevelDebug = logrus_wrap.LevelDebug
+	LevelInfo  = logrus_wrap.LevelInfo
+	LevelWarn  = logrus_wrap.LevelWarn
+	LevelError = logrus_wrap.LevelError
+
+	FormatText = logrus_wrap.FormatText
+	FormatJSON = logrus_wrap.FormatJSON
+
+	OverflowBlock   = logrus_wrap.OverflowBlock
+	OverflowDropLow = logrus_wrap.OverflowDropLow
+
+	RedactMobile = logrus_wrap.RedactMobile
+	RedactEmail  = logrus_wrap.RedactEmail
+	RedactToken  = logrus_wrap.RedactToken
+	RedactCookie = logrus_wrap.RedactCookie
 )
 
 // 这个 logger 用于包外访问，方便大家自定义日志路径与文件名等信息
 type Logger struct {
 	logger *logrus_wrap.Logger
+	name   string
+	level  levelReverter
 }
 
 func GenLogId() string {
@@ -83,7 +104,7 @@ func InitV4(opt ...LogOptionsFunc) error {
 		o(&opts)
 	}
 
-	if err := logrus_wrap.NewLogger(logrus_wrap.WithPath(opts.path), logrus_wrap.WithApp(opts.app), logrus_wrap.WithMaxLength(opts.maxLength), logrus_wrap.WithExpireDay(opts.expireDay)); err != nil {
+	if err := logrus_wrap.NewLogger(opts.wrapOptions()...); err != nil {
 		return err
 	}
 	return nil
@@ -95,12 +116,16 @@ func NewCustomLogger(opt ...LogOptionsFunc) (logger *Logger, err error) {
 		o(&opts)
 	}
 
-	logObj, err := logrus_wrap.NewCustomLogger(logrus_wrap.WithPath(opts.path), logrus_wrap.WithApp(opts.app), logrus_wrap.WithMaxLength(opts.maxLength), logrus_wrap.WithExpireDay(opts.expireDay), logrus_wrap.WithLogLevel(opts.level))
+	logObj, err := logrus_wrap.NewCustomLogger(opts.wrapOptions()...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Logger{logObj}, nil
+	logger = &Logger{logger: logObj, name: opts.name}
+	if opts.name != "" {
+		registerLogger(logger)
+	}
+	return logger, nil
 }
 
 type logOptions struct {
@@ -109,6 +134,42 @@ type logOptions struct {
 	level     string
 	expireDay int
 	maxLength int
+	format    string
+	asyncSize int
+	overflow  string
+
+	rotationSize int64
+	compress     bool
+	maxFiles     int
+	maxTotalSize int64
+
+	// name 不为空时注册为命名的Logger，可以通过LevelHandler修改level
+	name string
+
+	// sampling 按level配置的采样，依次转给logrus_wrap
+	sampling []logrus_wrap.LogOptionsFunc
+	sinks    []Sink
+	// redact 脱敏的配置，依次转给logrus_wrap
+	redact []logrus_wrap.LogOptionsFunc
+}
+
+func (opts logOptions) wrapOptions() []logrus_wrap.LogOptionsFunc {
+	wrapOpts := []logrus_wrap.LogOptionsFunc{
+		logrus_wrap.WithPath(opts.path),
+		logrus_wrap.WithApp(opts.app),
+		logrus_wrap.WithMaxLength(opts.maxLength),
+		logrus_wrap.WithExpireDay(opts.expireDay),
+		logrus_wrap.WithLogLevel(opts.level),
+		logrus_wrap.WithFormat(opts.format),
+		logrus_wrap.WithAsync(opts.asyncSize, opts.overflow),
+		logrus_wrap.WithRotationSize(opts.rotationSize),
+		logrus_wrap.WithCompress(opts.compress),
+		logrus_wrap.WithMaxFiles(opts.maxFiles),
+		logrus_wrap.WithMaxTotalSize(opts.maxTotalSize),
+		logrus_wrap.WithSinks(opts.sinks...),
+	}
+	wrapOpts = append(wrapOpts, opts.sampling...)
+	return append(wrapOpts, opts.redact...)
 }
 
 var defaultLogOptions = logOptions{
@@ -117,6 +178,8 @@ var defaultLogOptions = logOptions{
 	level:     logrus_wrap.LevelDebug,
 	expireDay: DefaultFileWriterMaxBackupDay,
 	maxLength: DefaultFileWriterMaxLength,
+	format:    FormatText,
+	overflow:  OverflowBlock,
 }
 
 type LogOptionsFunc func(*logOptions)
@@ -151,115 +214,180 @@ func WithLogLevel(v string) LogOptionsFunc {
 	}
 }
 
+// WithFormat 日志格式，FormatText（默认）或FormatJSON
+func WithFormat(v string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.format = v
+	}
+}
+
+// WithAsync 异步写入：日志先进入bufferSize条（<=0时为DefaultAsyncBufferSize）的缓冲，由后台goroutine写文件，
+// overflow为缓冲满时的策略，OverflowBlock等待，OverflowDropLow优先丢弃DEBUG、INFO，
+// 丢弃的条数可以通过Dropped获取，进程退出前需要调用Close
+func WithAsync(bufferSize int, overflow string) LogOptionsFunc {
+	return func(o *logOptions) {
+		if bufferSize <= 0 {
+			bufferSize = DefaultAsyncBufferSize
+		}
+		o.asyncSize = bufferSize
+		o.overflow = overflow
+	}
+}
+
+// WithRotationSize 单个文件超过size字节时切割，与按小时切割同时生效
+func WithRotationSize(size int64) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.rotationSize = size
+	}
+}
+
+// WithCompress 切割后的文件压缩为.gz
+func WithCompress(v bool) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.compress = v
+	}
+}
+
+// WithMaxFiles 最多保留的切割文件个数，一个app的.log和.log.wf合并计算
+func WithMaxFiles(n int) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxTotalSize 一个app的日志文件占用的最大字节数，超过时从最旧的切割文件开始删除
+func WithMaxTotalSize(size int64) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.maxTotalSize = size
+	}
+}
+
+// WithSampling 对level的日志采样，用于高频的错误日志：每个interval内同一个消息模板
+// （Infof等的format，Info等的第一个字符串参数）先输出first条，之后每thereafter条输出1条，
+// 被丢弃的条数定期以"suppressed X messages"输出。每个level分别配置，
+// interval<=0时为1秒，thereafter<=0时超过first之后全部丢弃
+func WithSampling(level string, interval time.Duration, first, thereafter int) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.sampling = append(o.sampling[:len(o.sampling):len(o.sampling)], logrus_wrap.WithSampling(level, interval, first, thereafter))
+	}
+}
+
+// WithName 只对NewCustomLogger生效，注册为命名的Logger，可以通过NamedLogger获取，
+// 通过LevelHandler查看和修改level，同名的Logger后注册的生效
+func WithName(name string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.name = name
+	}
+}
+
+// SetLogLevel 修改全局logger的level，会取消SetLogLevelFor还未恢复的修改
 func SetLogLevel(l string) {
-	logrus_wrap.SetLevel(l)
+	globalLevel.set(l, 0, logrus_wrap.GetLevel, logrus_wrap.SetLevel)
 }
 
+// LogIdFromContext 依次从incoming metadata、ctx value中取log-id
 func LogIdFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
 	md, _ := metadata.FromIncomingContext(ctx)
 	arr := md.Get(LogIDKey)
 	if len(arr) == 1 {
 		return arr[0], true
 	}
+	if logId, ok := ctx.Value(LogIDKey).(string); ok && logId != "" {
+		return logId, true
+	}
 	return "", false
 }
 
+// RemoteAddrNameFromContext 依次从incoming metadata、ctx value中取remote-addr
 func RemoteAddrNameFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
 	md, _ := metadata.FromIncomingContext(ctx)
 	remoteIp := md.Get(RemoteAddrName)
 	if len(remoteIp) == 1 {
 		return remoteIp[0]
 	}
+	if remoteAddr, ok := ctx.Value(RemoteAddrName).(string); ok {
+		return remoteAddr
+	}
 	return ""
 }
 
+// 以下函数都会自动带上ctx中的log-id、remote-addr以及WithFields附加的字段
+
 func Debugf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Debugf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logrus_wrap.Debugf(format, v...)
-	}
+	logrus_wrap.Logf(logrus_wrap.LevelDebug, contextFields(ctx, 0), format, v...)
 }
 
 func Debug(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Debug(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logrus_wrap.Debug(v...)
-	}
+	logrus_wrap.Log(logrus_wrap.LevelDebug, contextFields(ctx, 0), v...)
 }
 
 func Infof(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Infof(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logrus_wrap.Infof(format, v...)
-	}
+	logrus_wrap.Logf(logrus_wrap.LevelInfo, contextFields(ctx, 0), format, v...)
 }
 
 func Info(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Info(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logrus_wrap.Info(v...)
-	}
+	logrus_wrap.Log(logrus_wrap.LevelInfo, contextFields(ctx, 0), v...)
 }
 
 func Warnf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Warnf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logrus_wrap.Warnf(format, v...)
-	}
+	logrus_wrap.Logf(logrus_wrap.LevelWarn, contextFields(ctx, 0), format, v...)
 }
 
 func Warn(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Warn(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logrus_wrap.Warn(v...)
-	}
+	logrus_wrap.Log(logrus_wrap.LevelWarn, contextFields(ctx, 0), v...)
 }
 
 func Errorf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Errorf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logrus_wrap.Errorf(format, v...)
-	}
+	logrus_wrap.Logf(logrus_wrap.LevelError, contextFields(ctx, 0), format, v...)
 }
 
 func Error(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logrus_wrap.Error(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logrus_wrap.Error(v...)
-	}
+	logrus_wrap.Log(logrus_wrap.LevelError, contextFields(ctx, 0), v...)
 }
 
+// Close 写完异步缓冲中的日志并关闭文件，进程退出前调用，之后的日志输出到标准错误
 func Close() {
+	_ = logrus_wrap.Close()
+}
+
+// Flush 等待异步缓冲中的日志写完
+func Flush() {
+	logrus_wrap.Flush()
+}
+
+// Dropped 异步写入时缓冲满被丢弃的条数，key为level
+func Dropped() map[string]uint64 {
+	return logrus_wrap.Dropped()
+}
+
+func (logObj *Logger) Close() error {
+	unregisterLogger(logObj)
+	logObj.level.stop()
+	return logObj.logger.Close()
+}
+
+func (logObj *Logger) Flush() {
+	logObj.logger.Flush()
+}
+
+func (logObj *Logger) Dropped() map[string]uint64 {
+	return logObj.logger.Dropped()
 }
 
 func InfofArray(ctx context.Context, format string, array []string, splitSize int) {
 	if len(array) == 0 {
 		return
 	}
-	logId, ok := LogIdFromContext(ctx)
+	fields := contextFields(ctx, 0)
 
 	if len(array) < splitSize {
-		if ok {
-			logrus_wrap.Infof(fmt.Sprintf("[%s] %s", logId, format), array)
-		} else {
-			logrus_wrap.Infof(format, array)
-		}
+		logrus_wrap.Logf(logrus_wrap.LevelInfo, fields, format, array)
 		return
 	}
 
@@ -267,109 +395,56 @@ func InfofArray(ctx context.Context, format string, array []string, splitSize in
 	for _, v := range array {
 		tmp = append(tmp, v)
 		if len(tmp) == splitSize {
-			if ok {
-				logrus_wrap.Infof(fmt.Sprintf("[%s] %s", logId, format), tmp)
-			} else {
-				logrus_wrap.Infof(format, tmp)
-			}
-
+			logrus_wrap.Logf(logrus_wrap.LevelInfo, fields, format, tmp)
 			tmp = tmp[:0]
 		}
 	}
 
 	if len(tmp) > 0 {
-		if ok {
-			logrus_wrap.Infof(fmt.Sprintf("[%s] %s", logId, format), tmp)
-		} else {
-			logrus_wrap.Infof(format, tmp)
-		}
+		logrus_wrap.Logf(logrus_wrap.LevelInfo, fields, format, tmp)
 	}
 }
 
 func (logObj *Logger) Debugf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Debugf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logObj.logger.Debugf(format, v...)
-	}
+	logObj.logger.Logf(logrus_wrap.LevelDebug, contextFields(ctx, 0), format, v...)
 }
 
 func (logObj *Logger) Debug(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Debug(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logObj.logger.Debug(v...)
-	}
+	logObj.logger.Log(logrus_wrap.LevelDebug, contextFields(ctx, 0), v...)
 }
 
 func (logObj *Logger) Infof(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Infof(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logObj.logger.Infof(format, v...)
-	}
+	logObj.logger.Logf(logrus_wrap.LevelInfo, contextFields(ctx, 0), format, v...)
 }
 
 func (logObj *Logger) Info(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Info(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logObj.logger.Info(v...)
-	}
+	logObj.logger.Log(logrus_wrap.LevelInfo, contextFields(ctx, 0), v...)
 }
 
 func (logObj *Logger) Warnf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Warnf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logObj.logger.Warnf(format, v...)
-	}
+	logObj.logger.Logf(logrus_wrap.LevelWarn, contextFields(ctx, 0), format, v...)
 }
 
 func (logObj *Logger) Warn(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Warn(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logObj.logger.Warn(v...)
-	}
+	logObj.logger.Log(logrus_wrap.LevelWarn, contextFields(ctx, 0), v...)
 }
 
 func (logObj *Logger) Errorf(ctx context.Context, format string, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Errorf(fmt.Sprintf("[%s] %s", logId, format), v...)
-	} else {
-		logObj.logger.Errorf(format, v...)
-	}
+	logObj.logger.Logf(logrus_wrap.LevelError, contextFields(ctx, 0), format, v...)
 }
 
 func (logObj *Logger) Error(ctx context.Context, v ...interface{}) {
-	logId, ok := LogIdFromContext(ctx)
-	if ok {
-		logObj.logger.Error(fmt.Sprintf("[%s]", logId) + " " + fmt.Sprint(v...))
-	} else {
-		logObj.logger.Error(v...)
-	}
+	logObj.logger.Log(logrus_wrap.LevelError, contextFields(ctx, 0), v...)
 }
 
 func (logObj *Logger) InfofArray(ctx context.Context, format string, array []string, splitSize int) {
 	if len(array) == 0 {
 		return
 	}
-	logId, ok := LogIdFromContext(ctx)
+	fields := contextFields(ctx, 0)
 
 	if len(array) < splitSize {
-		if ok {
-			logObj.logger.Infof(fmt.Sprintf("[%s] %s", logId, format), array)
-		} else {
-			logObj.logger.Infof(format, array)
-		}
+		logObj.logger.Logf(logrus_wrap.LevelInfo, fields, format, array)
 		return
 	}
 
@@ -377,21 +452,12 @@ func (logObj *Logger) InfofArray(ctx context.Context, format string, array []str
 	for _, v := range array {
 		tmp = append(tmp, v)
 		if len(tmp) == splitSize {
-			if ok {
-				logObj.logger.Infof(fmt.Sprintf("[%s] %s", logId, format), tmp)
-			} else {
-				logObj.logger.Infof(format, tmp)
-			}
-
+			logObj.logger.Logf(logrus_wrap.LevelInfo, fields, format, tmp)
 			tmp = tmp[:0]
 		}
 	}
 
 	if len(tmp) > 0 {
-		if ok {
-			logObj.logger.Infof(fmt.Sprintf("[%s] %s", logId, format), tmp)
-		} else {
-			logObj.logger.Infof(format, tmp)
-		}
+		logObj.logger.Logf(logrus_wrap.LevelInfo, fields, format, tmp)
 	}
 }
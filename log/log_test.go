@@ -3,7 +3,10 @@ package log
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -61,3 +64,51 @@ func TestCustomLog(t *testing.T) {
 	logObj.Errorf(context.Background(), "logrus test %s", getRandomString(10))
 	logObj.Errorf(context.Background(), "logrus test %s", getRandomString(2048))
 }
+
+func TestAsyncLogger(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithProject("async"), WithPath(dir), WithAsync(16, OverflowDropLow))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		logObj.Infof(context.Background(), "line %d", i)
+	}
+	// Close之前的日志都会写入文件
+	if err := logObj.Close(); err != nil {
+		t.Fatalf("close err: %s", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "async.log"))
+	if err != nil {
+		t.Fatalf("read log err: %s", err)
+	}
+	if n := strings.Count(string(b), "\n"); n != 10-int(logObj.Dropped()[LevelInfo]) {
+		t.Errorf("unexpected lines %d dropped %v", n, logObj.Dropped())
+	}
+}
+
+func TestSamplingLogger(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithProject("sampling"), WithPath(dir), WithSampling(LevelError, time.Hour, 2, 0))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		logObj.Errorf(context.Background(), "query failed %d", i)
+	}
+	logObj.Infof(context.Background(), "query ok")
+	if err := logObj.Close(); err != nil {
+		t.Fatalf("close err: %s", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "sampling.log"))
+	if err != nil {
+		t.Fatalf("read log err: %s", err)
+	}
+	content := string(b)
+	if n := strings.Count(content, "query failed"); n != 3 {
+		t.Errorf("unexpected lines %d:\n%s", n, content)
+	}
+	if !strings.Contains(content, "suppressed 8 messages: query failed %d") || !strings.Contains(content, "query ok") {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+}
@@ -0,0 +1,192 @@
+package logrus_wrap
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OverflowBlock 缓冲满时等待后台写入
+	OverflowBlock = "block"
+	// OverflowDropLow 缓冲满时丢弃DEBUG、INFO，WARN、ERROR优先挤掉缓冲中的DEBUG、INFO，
+	// 缓冲中全是WARN、ERROR时仍然等待
+	OverflowDropLow = "drop"
+)
+
+const defaultAsyncBufferSize = 8192
+
+type asyncEntry struct {
+	level logrus.Level
+	data  []byte
+}
+
+// asyncWriter 有界的环形缓冲，由后台goroutine写入底层的writer，
+// 磁盘、syslog变慢时不会直接阻塞请求
+type asyncWriter struct {
+	writer   entryWriter
+	overflow string
+
+	mutex    sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	entries  []asyncEntry
+	head     int
+	size     int
+	writing  bool
+	closed   bool
+	done     chan struct{}
+
+	dropped [logrus.TraceLevel + 1]uint64
+}
+
+func newAsyncWriter(writer entryWriter, bufferSize int, overflow string) *asyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = defaultAsyncBufferSize
+	}
+	w := &asyncWriter{
+		writer:   writer,
+		overflow: overflow,
+		entries:  make([]asyncEntry, bufferSize),
+		done:     make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mutex)
+	w.notFull = sync.NewCond(&w.mutex)
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) WriteEntry(level logrus.Level, p []byte) error {
+	// p来自logrus的buffer池，需要复制
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return writeClosed(data)
+	}
+	for w.size == len(w.entries) {
+		if w.overflow == OverflowDropLow {
+			if isLowLevel(level) {
+				atomic.AddUint64(&w.dropped[level], 1)
+				return nil
+			}
+			if w.evictLow() {
+				break
+			}
+		}
+		w.notFull.Wait()
+		if w.closed {
+			return writeClosed(data)
+		}
+	}
+	w.entries[(w.head+w.size)%len(w.entries)] = asyncEntry{level: level, data: data}
+	w.size++
+	w.notEmpty.Signal()
+	return nil
+}
+
+// writeClosed Close之后底层writer已经关闭，输出到标准错误，不丢失
+func writeClosed(data []byte) error {
+	_, err := os.Stderr.Write(data)
+	return err
+}
+
+// evictLow 丢弃缓冲中最早的一条DEBUG、INFO，没有时返回false
+func (w *asyncWriter) evictLow() bool {
+	n := len(w.entries)
+	for i := 0; i < w.size; i++ {
+		idx := (w.head + i) % n
+		if !isLowLevel(w.entries[idx].level) {
+			continue
+		}
+		atomic.AddUint64(&w.dropped[w.entries[idx].level], 1)
+		// 后面的依次前移
+		for j := i; j < w.size-1; j++ {
+			w.entries[(w.head+j)%n] = w.entries[(w.head+j+1)%n]
+		}
+		w.size--
+		w.entries[(w.head+w.size)%n] = asyncEntry{}
+		return true
+	}
+	return false
+}
+
+func isLowLevel(level logrus.Level) bool {
+	return level >= logrus.InfoLevel
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	batch := make([]asyncEntry, 0, len(w.entries))
+	for {
+		w.mutex.Lock()
+		for w.size == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.size == 0 && w.closed {
+			w.mutex.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for w.size > 0 {
+			batch = append(batch, w.entries[w.head])
+			w.entries[w.head] = asyncEntry{}
+			w.head = (w.head + 1) % len(w.entries)
+			w.size--
+		}
+		w.writing = true
+		w.notFull.Broadcast()
+		w.mutex.Unlock()
+
+		for _, e := range batch {
+			if err := w.writer.WriteEntry(e.level, e.data); err != nil {
+				fmt.Fprintf(os.Stderr, "[logrus] async write err: %s\n", err)
+			}
+		}
+
+		w.mutex.Lock()
+		w.writing = false
+		w.notFull.Broadcast()
+		w.mutex.Unlock()
+	}
+}
+
+// Flush 等待缓冲中的日志写完
+func (w *asyncWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for (w.size > 0 || w.writing) && !w.closed {
+		w.notFull.Wait()
+	}
+}
+
+func (w *asyncWriter) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mutex.Unlock()
+
+	<-w.done
+	return w.writer.Close()
+}
+
+// Dropped 按level统计的丢弃条数
+func (w *asyncWriter) Dropped() map[string]uint64 {
+	dropped := make(map[string]uint64)
+	for level, name := range levelMapper {
+		if n := atomic.LoadUint64(&w.dropped[level]); n > 0 {
+			dropped[name] += n
+		}
+	}
+	return dropped
+}
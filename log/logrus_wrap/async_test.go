@@ -0,0 +1,128 @@
+package logrus_wrap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockingWriter 第一次写入时阻塞，直到release被关闭
+type blockingWriter struct {
+	mutex   sync.Mutex
+	lines   []string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestAsyncWriterDropLow(t *testing.T) {
+	bw := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	w := newAsyncWriter(&syncWriter{writer: bw}, 2, OverflowDropLow)
+
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("first"))
+	<-bw.started
+
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("info1"))
+	_ = w.WriteEntry(logrus.DebugLevel, []byte("debug1"))
+	// 缓冲已满，DEBUG、INFO直接丢弃
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("info2"))
+	// ERROR挤掉缓冲中最早的INFO
+	_ = w.WriteEntry(logrus.ErrorLevel, []byte("error1"))
+
+	close(bw.release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("close err: %s", err)
+	}
+
+	if actual := strings.Join(bw.lines, ","); actual != "first,debug1,error1" {
+		t.Errorf("unexpected lines %s", actual)
+	}
+	dropped := w.Dropped()
+	if dropped[LevelInfo] != 2 || dropped[LevelDebug] != 0 {
+		t.Errorf("unexpected dropped %v", dropped)
+	}
+
+	// Close之后输出到标准错误，不再写入底层writer
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("after\n"))
+	if bw.lines[len(bw.lines)-1] == "after\n" {
+		t.Errorf("expect no write to closed writer")
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	bw := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	w := newAsyncWriter(&syncWriter{writer: bw}, 1, OverflowBlock)
+
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("first"))
+	<-bw.started
+	_ = w.WriteEntry(logrus.InfoLevel, []byte("second"))
+
+	done := make(chan struct{})
+	go func() {
+		_ = w.WriteEntry(logrus.InfoLevel, []byte("third"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("expect block when buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(bw.release)
+	<-done
+	w.Flush()
+	if actual := strings.Join(bw.lines, ","); actual != "first,second,third" {
+		t.Errorf("unexpected lines %s", actual)
+	}
+	if len(w.Dropped()) != 0 {
+		t.Errorf("expect nothing dropped")
+	}
+	_ = w.Close()
+}
+
+func TestLogAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithPath(dir), WithAsync(16, OverflowBlock))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	logObj.Log(LevelInfo, nil, "before close")
+
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatalf("create err: %s", err)
+	}
+	defer stderr.Close()
+	origin := os.Stderr
+	os.Stderr = stderr
+	_ = logObj.Close()
+	os.Stderr = origin
+
+	logObj.Log(LevelInfo, nil, "after close")
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if !strings.Contains(string(b), "before close") || strings.Contains(string(b), "after close") {
+		t.Errorf("unexpected app.log:\n%s", b)
+	}
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "stderr"))
+	if !strings.Contains(string(b), "after close") {
+		t.Errorf("expect log after close in stderr, actual:\n%s", b)
+	}
+}
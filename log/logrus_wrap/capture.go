@@ -0,0 +1,86 @@
+package logrus_wrap
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Entry 内存中捕获的一条日志，Message和Fields是脱敏之后的内容
+type Entry struct {
+	Level   string
+	Time    time.Time
+	Message string
+	LogId   string
+	Fields  Fields
+}
+
+// Capture 把日志保存在内存中，用于测试
+type Capture struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+func (c *Capture) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (c *Capture) Fire(entry *logrus.Entry) error {
+	e := Entry{
+		Level:   levelMapper[entry.Level],
+		Time:    entry.Time,
+		Message: strings.TrimSuffix(entry.Message, "\n"),
+		Fields:  make(Fields, len(entry.Data)),
+	}
+	for k, v := range entry.Data {
+		e.Fields[k] = v
+	}
+	if logId, ok := entry.Data[KeyLogId]; ok {
+		e.LogId, _ = logId.(string)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = append(c.entries, e)
+	return nil
+}
+
+// Entries 按输出顺序返回
+func (c *Capture) Entries() []Entry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]Entry(nil), c.entries...)
+}
+
+func (c *Capture) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = nil
+}
+
+// NewCapture 创建只输出到内存的logger并替换全局logger，不创建任何文件，
+// WithSinks之外的配置（level、采样、脱敏等）同样生效，restore恢复之前的全局logger
+func NewCapture(opt ...LogOptionsFunc) (c *Capture, restore func(), err error) {
+	opts := defaultLogOptions
+	for _, o := range opt {
+		o(&opts)
+	}
+	opts.sinks = nil
+
+	l, err := newLogger(opts, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	c = &Capture{}
+	// 在脱敏的hook之后注册
+	l.logger.AddHook(c)
+	l.SetLevel(opts.level)
+
+	prev := logObj
+	logObj = l
+	return c, func() {
+		logObj = prev
+		_ = l.Close()
+	}, nil
+}
@@ -0,0 +1,92 @@
+package logrus_wrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jsonFormatter 每行一个json对象，固定的key在前，字段按key排序，
+// 字段与固定的key重名时加上fields.前缀
+type jsonFormatter struct {
+	maxLength int
+}
+
+var jsonReservedKeys = map[string]bool{
+	"level":       true,
+	"time":        true,
+	"func":        true,
+	"caller":      true,
+	"msg":         true,
+	KeyLogId:      true,
+	KeyRemoteAddr: true,
+}
+
+func (f *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var b *bytes.Buffer
+	if entry.Buffer != nil {
+		b = entry.Buffer
+	} else {
+		b = &bytes.Buffer{}
+	}
+
+	msg := strings.TrimSuffix(entry.Message, "\n")
+	if f.maxLength > 0 && len(msg) > f.maxLength {
+		msg = msg[:f.maxLength]
+	}
+
+	b.WriteByte('{')
+	f.appendField(b, "level", strings.ToUpper(entry.Level.String()), true)
+	f.appendField(b, "time", entry.Time.Format(defaultFileWriterMsgSuffixTimeString), false)
+	if entry.Caller = getCaller(); entry.Caller != nil {
+		f.appendField(b, "func", entry.Caller.Function, false)
+		f.appendField(b, "caller", fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line), false)
+	}
+	for _, k := range []string{KeyLogId, KeyRemoteAddr} {
+		if v, ok := entry.Data[k]; ok {
+			f.appendField(b, k, v, false)
+		}
+	}
+	f.appendField(b, "msg", msg, false)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		if k != KeyLogId && k != KeyRemoteAddr {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := k
+		if jsonReservedKeys[k] {
+			name = "fields." + k
+		}
+		f.appendField(b, name, entry.Data[k], false)
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+func (f *jsonFormatter) appendField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+
+	// error直接序列化是{}
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(v)
+}
@@ -3,10 +3,13 @@ package logrus_wrap
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +24,20 @@ const (
 	defaultFileWriterMsgSuffixTimeString = "06-01-02 15:04:05.999"
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// 结构化日志中由formatter单独处理的字段
+const (
+	KeyLogId      = "log-id"
+	KeyRemoteAddr = "remote-addr"
+)
+
+// Fields 结构化日志的字段
+type Fields map[string]interface{}
+
 const (
 	LevelDebug = "DEBUG"
 	LevelInfo  = "INFO"
@@ -35,16 +52,23 @@ var levelMapperRev = map[string]logrus.Level{
 	LevelError: logrus.ErrorLevel,
 }
 
-var logObj *logger = nil
-
-type logger struct {
-	iWriter *logrus.Logger
-	fWriter *logrus.Logger
+var levelMapper = map[logrus.Level]string{
+	logrus.DebugLevel: LevelDebug,
+	logrus.InfoLevel:  LevelInfo,
+	logrus.WarnLevel:  LevelWarn,
+	logrus.ErrorLevel: LevelError,
 }
 
+var logObj *Logger = nil
+
 // 这个 logger 用于包外访问，方便大家自定义日志路径与文件名等信息
 type Logger struct {
-	logger *logrus.Logger
+	logger   *logrus.Logger
+	writers  []entryWriter
+	sampler  *sampler
+	redactor *redactor
+	// formatter Close之后输出到标准错误时使用
+	formatter logrus.Formatter
 }
 
 type logOptions struct {
@@ -53,6 +77,23 @@ type logOptions struct {
 	maxLength int
 	expireDay int
 	level     string
+	format    string
+	// asyncSize大于0时使用异步写入
+	asyncSize int
+	overflow  string
+	// rotationSize大于0时按大小切割，与按小时切割同时生效
+	rotationSize int64
+	compress     bool
+	maxFiles     int
+	maxTotalSize int64
+	sampling     []samplingRule
+	// sinks 为空时使用默认的文件
+	sinks []Sink
+	// redact 为true时在输出到sink之前脱敏
+	redact          bool
+	redactDetectors []string
+	redactKeys      []string
+	redactFunc      RedactFunc
 }
 
 var defaultLogOptions = logOptions{
@@ -61,6 +102,8 @@ var defaultLogOptions = logOptions{
 	level:     LevelDebug,
 	maxLength: defaultFileWriterMaxLength,
 	expireDay: defaultFileWriterExpireDay,
+	format:    FormatText,
+	overflow:  OverflowBlock,
 }
 
 type LogOptionsFunc func(*logOptions)
@@ -95,6 +138,90 @@ func WithLogLevel(l string) LogOptionsFunc {
 	}
 }
 
+// WithFormat 日志格式，FormatText（默认）或FormatJSON
+func WithFormat(v string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.format = v
+	}
+}
+
+// WithAsync 异步写入，bufferSize为缓冲的条数，<=0时同步写入，
+// overflow为缓冲满时的策略：OverflowBlock或OverflowDropLow
+func WithAsync(bufferSize int, overflow string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.asyncSize = bufferSize
+		o.overflow = overflow
+	}
+}
+
+// WithRotationSize 单个文件超过size字节时切割，切割后的文件名追加.1、.2
+func WithRotationSize(size int64) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.rotationSize = size
+	}
+}
+
+// WithCompress 切割后的文件压缩为.gz
+func WithCompress(v bool) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.compress = v
+	}
+}
+
+// WithMaxFiles 最多保留的切割文件个数，一个Logger的所有文件（例如.log和.log.wf）合并计算
+func WithMaxFiles(n int) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxTotalSize 一个Logger的所有日志文件占用的最大字节数，超过时从最旧的切割文件开始删除
+func WithMaxTotalSize(size int64) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.maxTotalSize = size
+	}
+}
+
+// WithSampling 对level的日志采样：每个interval内同一个消息模板先输出first条，之后每thereafter条输出1条，
+// 被丢弃的条数定期以"suppressed X messages"输出。消息模板是Logf的format，Log的第一个字符串参数，
+// 每个level分别配置，interval<=0时为1秒，thereafter<=0时超过first之后全部丢弃
+func WithSampling(level string, interval time.Duration, first, thereafter int) LogOptionsFunc {
+	return func(o *logOptions) {
+		rules := make([]samplingRule, 0, len(o.sampling)+1)
+		for _, r := range o.sampling {
+			if r.level != level {
+				rules = append(rules, r)
+			}
+		}
+		o.sampling = append(rules, samplingRule{level: level, interval: interval, first: first, thereafter: thereafter})
+	}
+}
+
+// WithRedact 开启脱敏，detectors为使用的内置规则：RedactMobile、RedactEmail、RedactToken、RedactCookie，
+// 为空时使用全部规则。WithRedactKeys、WithRedactFunc同样会开启脱敏
+func WithRedact(detectors ...string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = true
+		o.redactDetectors = append([]string(nil), detectors...)
+	}
+}
+
+// WithRedactKeys 追加结构化字段中整体替换为***的key，默认包括password、token、cookie等
+func WithRedactKeys(keys ...string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = true
+		o.redactKeys = append(o.redactKeys[:len(o.redactKeys):len(o.redactKeys)], keys...)
+	}
+}
+
+// WithRedactFunc 自定义的脱敏规则，在内置规则之后执行
+func WithRedactFunc(fn RedactFunc) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = true
+		o.redactFunc = fn
+	}
+}
+
 func NewLogger(opt ...LogOptionsFunc) error {
 	if logObj != nil {
 		fmt.Printf("[logrus] logObj is already initialized\n")
@@ -105,25 +232,19 @@ func NewLogger(opt ...LogOptionsFunc) error {
 		o(&opts)
 	}
 
-	if err := os.MkdirAll(opts.path, os.FileMode(0755)); err != nil {
-		fmt.Printf("[logrus] mkdir failed, path: %s\n", opts.path)
-		return err
-	}
-
-	iWriter, err := newWriter(opts.path, opts.app+".log", opts.expireDay, opts.maxLength)
-	if err != nil {
-		return err
+	sinks := opts.sinks
+	if len(sinks) == 0 {
+		// 默认DEBUG、INFO写入.log，WARN、ERROR写入.log.wf
+		sinks = []Sink{
+			FileSink(opts.app+".log", WithSinkMaxLevel(LevelInfo)),
+			FileSink(opts.app+".log.wf", WithSinkLevel(LevelWarn)),
+		}
 	}
-
-	fWriter, err := newWriter(opts.path, opts.app+".log.wf", opts.expireDay, opts.maxLength)
+	l, err := newLogger(opts, sinks)
 	if err != nil {
 		return err
 	}
-
-	logObj = &logger{
-		iWriter: iWriter,
-		fWriter: fWriter,
-	}
+	logObj = l
 
 	SetLevel(opts.level)
 	return nil
@@ -135,72 +256,295 @@ func NewCustomLogger(opt ...LogOptionsFunc) (*Logger, error) {
 		o(&opts)
 	}
 
-	if err := os.MkdirAll(opts.path, os.FileMode(0755)); err != nil {
-		fmt.Printf("[logrus] mkdir failed, path: %s\n", opts.path)
-		return nil, err
+	sinks := opts.sinks
+	if len(sinks) == 0 {
+		sinks = []Sink{FileSink(opts.app + ".log")}
 	}
-
-	writer, err := newWriter(opts.path, opts.app+".log", opts.expireDay, opts.maxLength)
+	l, err := newLogger(opts, sinks)
 	if err != nil {
 		return nil, err
 	}
 
-	if v, ok := levelMapperRev[opts.level]; ok {
-		writer.SetLevel(v)
-	} else {
-		fmt.Printf("[logrus] unknown log level %s, now using DEBUG\n", opts.level)
-		writer.SetLevel(logrus.DebugLevel)
+	l.SetLevel(opts.level)
+	return l, nil
+}
+
+func SetLevel(level string) {
+	if logObj == nil {
+		fmt.Printf("[logrus] logObj is uninitialized, set level error\n")
+		return
 	}
+	logObj.SetLevel(level)
+}
 
-	return &Logger{
-		writer,
-	}, nil
+// GetLevel 没有初始化时所有日志都输出到标准输出，返回LevelDebug
+func GetLevel() string {
+	if logObj == nil {
+		return LevelDebug
+	}
+	return logObj.GetLevel()
 }
 
-func SetLevel(level string) {
+func (logObj *Logger) SetLevel(level string) {
 	if logObj == nil {
 		fmt.Printf("[logrus] logObj is uninitialized, set level error\n")
 		return
 	}
 	if v, ok := levelMapperRev[level]; ok {
-		logObj.iWriter.SetLevel(v)
-		logObj.fWriter.SetLevel(v)
+		logObj.logger.SetLevel(v)
 	} else {
 		fmt.Printf("[logrus] unknown log level %s, now using DEBUG\n", level)
-		logObj.iWriter.SetLevel(logrus.DebugLevel)
-		logObj.fWriter.SetLevel(logrus.DebugLevel)
+		logObj.logger.SetLevel(logrus.DebugLevel)
 	}
 }
 
-func newWriter(filepath, fileName string, expireDay, maxLength int) (logger *logrus.Logger, err error) {
-	// 转为绝对路径处理
-	var fileWithFullPath string
-	if strings.HasPrefix(filepath, "/") {
-		fileWithFullPath = path.Join(filepath, fileName)
-	} else {
-		pwd, err := os.Getwd()
-		if err != nil {
-			fmt.Printf("[logrus] os.getwd err, err: %s\n", err)
+func (logObj *Logger) GetLevel() string {
+	if logObj == nil {
+		return LevelDebug
+	}
+	return levelMapper[logObj.logger.GetLevel()]
+}
+
+// absDir 转为绝对路径处理
+func absDir(dir string) (string, error) {
+	if strings.HasPrefix(dir, "/") {
+		return dir, nil
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("[logrus] os.getwd err, err: %s\n", err)
+		return "", err
+	}
+	return path.Join(pwd, dir), nil
+}
+
+// newLogger 每个sink是logrus.Logger的一个hook，按sink的level范围触发
+func newLogger(opts logOptions, sinks []Sink) (*Logger, error) {
+	var (
+		dir       string
+		retention *fileRetention
+	)
+	for _, sink := range sinks {
+		if sink.kind != sinkFile || dir != "" {
+			continue
+		}
+		// 只有输出到文件时才需要目录，容器中只输出到标准输出时不创建
+		if err := os.MkdirAll(opts.path, os.FileMode(0755)); err != nil {
+			fmt.Printf("[logrus] mkdir failed, path: %s\n", opts.path)
+			return nil, err
+		}
+		var err error
+		if dir, err = absDir(opts.path); err != nil {
 			return nil, err
 		}
-		fileWithFullPath = path.Join(pwd, filepath, fileName)
+		retention = newFileRetention(dir, opts)
 	}
-	logger = logrus.New()
-	writer, err := rotatelogs.New(
-		fileWithFullPath+".%Y%m%d%H",
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.Formatter = nopFormatter{}
+	// hook按注册顺序执行，脱敏在所有sink之前
+	r := newRedactor(opts)
+	if r != nil {
+		logger.AddHook(&redactHook{redactor: r})
+	}
+	writers := make([]entryWriter, 0, len(sinks))
+	for _, sink := range sinks {
+		out, err := newSinkWriter(opts, sink, dir, retention)
+		if err != nil {
+			_ = closeWriters(writers)
+			return nil, err
+		}
+		writers = append(writers, out)
+		format := sink.format
+		if format == "" {
+			format = opts.format
+		}
+		logger.AddHook(&outputHook{
+			formatter: newFormatter(format, opts.maxLength),
+			writer:    out,
+			levels:    sink.levels(),
+		})
+	}
+
+	l := &Logger{
+		logger:    logger,
+		writers:   writers,
+		redactor:  r,
+		formatter: newFormatter(opts.format, opts.maxLength),
+	}
+	l.sampler = newSampler(opts.sampling, func(level logrus.Level, msg string) {
+		if l.logger.IsLevelEnabled(level) {
+			l.logger.Log(level, msg)
+		}
+	})
+	return l, nil
+}
+
+func newFileWriter(opts logOptions, dir, fileName string, retention *fileRetention) (*rotatelogs.RotateLogs, error) {
+	fileWithFullPath := path.Join(dir, fileName)
+	rotateOpts := []rotatelogs.Option{
 		rotatelogs.WithLinkName(fileWithFullPath),
-		rotatelogs.WithMaxAge(time.Duration(expireDay)*24*time.Hour),
+		rotatelogs.WithMaxAge(time.Duration(opts.expireDay) * 24 * time.Hour),
 		rotatelogs.WithRotationTime(defaultFileWriterRotationTime),
-	)
+	}
+	if opts.rotationSize > 0 {
+		rotateOpts = append(rotateOpts, rotatelogs.WithRotationSize(opts.rotationSize))
+	}
+	if retention != nil {
+		rotateOpts = append(rotateOpts, rotatelogs.WithHandler(retention))
+	}
+	writer, err := rotatelogs.New(fileWithFullPath+".%Y%m%d%H", rotateOpts...)
 	if err != nil {
 		fmt.Printf("[logrus] failed to create rotatelogs: %s\n", err)
 		return nil, err
 	}
+	if retention != nil {
+		retention.add(fileName, writer)
+	}
+	return writer, nil
+}
+
+// Close 输出采样的汇总，写完异步缓冲中的日志并关闭所有sink，
+// 文件等资源已经释放，之后的日志输出到标准错误
+func Close() error {
+	return logObj.Close()
+}
+
+func (logObj *Logger) Close() error {
+	if logObj == nil {
+		return nil
+	}
+	logObj.sampler.Close()
+
+	// 先替换hook再关闭sink：hook在logrus.Logger的锁中执行，替换之后不会再有日志写到关闭的sink
+	hooks := make(logrus.LevelHooks)
+	if logObj.redactor != nil {
+		hooks.Add(&redactHook{redactor: logObj.redactor})
+	}
+	hooks.Add(&outputHook{
+		formatter: logObj.formatter,
+		writer:    &syncWriter{writer: nopCloser{os.Stderr}},
+		levels:    logrus.AllLevels,
+	})
+	logObj.logger.ReplaceHooks(hooks)
+	return closeWriters(logObj.writers)
+}
+
+func closeWriters(writers []entryWriter) error {
+	var firstErr error
+	for _, w := range writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Flush 等待异步缓冲中的日志写完，同步写入时直接返回
+func Flush() {
+	if logObj != nil {
+		flushWriters(logObj.writers)
+	}
+}
+
+func (logObj *Logger) Flush() {
+	if logObj != nil {
+		flushWriters(logObj.writers)
+	}
+}
+
+func flushWriters(writers []entryWriter) {
+	for _, w := range writers {
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// Dropped 异步写入时缓冲满、发送到远端失败被丢弃的条数，按level统计
+func Dropped() map[string]uint64 {
+	if logObj == nil {
+		return map[string]uint64{}
+	}
+	return droppedWriters(logObj.writers)
+}
+
+func (logObj *Logger) Dropped() map[string]uint64 {
+	if logObj == nil {
+		return map[string]uint64{}
+	}
+	return droppedWriters(logObj.writers)
+}
+
+func droppedWriters(writers []entryWriter) map[string]uint64 {
+	dropped := make(map[string]uint64)
+	for _, w := range writers {
+		if d, ok := w.(dropCounter); ok {
+			for level, n := range d.Dropped() {
+				dropped[level] += n
+			}
+		}
+	}
+	return dropped
+}
+
+func newFormatter(format string, maxLength int) logrus.Formatter {
+	switch format {
+	case FormatJSON:
+		return &jsonFormatter{maxLength: maxLength}
+	case FormatText, "":
+	default:
+		fmt.Printf("[logrus] unknown log format %s, now using text\n", format)
+	}
+	return &textFormatter{maxLength: maxLength}
+}
+
+// Log 结构化日志，fields由formatter输出为独立的key，v的拼接方式与Info相同
+func Log(level string, fields Fields, v ...interface{}) {
+	logObj.Log(level, fields, v...)
+}
+
+func Logf(level string, fields Fields, format string, v ...interface{}) {
+	logObj.Logf(level, fields, format, v...)
+}
+
+func (logObj *Logger) Log(level string, fields Fields, v ...interface{}) {
+	l := parseLevel(level)
+	if logObj == nil {
+		printUninitialized(l, fields, fmt.Sprint(v...))
+		return
+	}
+	if logObj.logger.IsLevelEnabled(l) && logObj.sampler.allow(l, logTemplate(v)) {
+		logObj.logger.WithFields(logrus.Fields(fields)).Log(l, v...)
+	}
+}
+
+func (logObj *Logger) Logf(level string, fields Fields, format string, v ...interface{}) {
+	l := parseLevel(level)
+	if logObj == nil {
+		printUninitialized(l, fields, fmt.Sprintf(format, v...))
+		return
+	}
+	if logObj.logger.IsLevelEnabled(l) && logObj.sampler.allow(l, format) {
+		logObj.logger.WithFields(logrus.Fields(fields)).Logf(l, format, v...)
+	}
+}
+
+// printUninitialized 没有初始化时输出到标准输出
+func printUninitialized(level logrus.Level, fields Fields, msg string) {
+	prefix := "[" + strings.ToUpper(level.String()) + "]"
+	if logId, ok := fields[KeyLogId]; ok {
+		prefix += fmt.Sprintf(" [%v]", logId)
+	}
+	fmt.Println(prefix, msg)
+}
 
-	logger.SetOutput(writer)
-	formatter := &textFormatter{maxLength: maxLength}
-	logger.Formatter = formatter
-	return
+func parseLevel(level string) logrus.Level {
+	if v, ok := levelMapperRev[level]; ok {
+		return v
+	}
+	return logrus.InfoLevel
 }
 
 func Debug(v ...interface{}) {
@@ -208,8 +552,8 @@ func Debug(v ...interface{}) {
 		fmt.Println(append([]interface{}{"[DEBUG]"}, v...)...)
 		return
 	}
-	if logObj.iWriter.IsLevelEnabled(logrus.DebugLevel) {
-		logObj.iWriter.Debug(v...)
+	if logObj.logger.IsLevelEnabled(logrus.DebugLevel) {
+		logObj.logger.Debug(v...)
 	}
 }
 
@@ -218,8 +562,8 @@ func Debugf(format string, v ...interface{}) {
 		fmt.Printf("[DEBUG] "+format+"\n", v...)
 		return
 	}
-	if logObj.iWriter.IsLevelEnabled(logrus.DebugLevel) {
-		logObj.iWriter.Debugf(format, v...)
+	if logObj.logger.IsLevelEnabled(logrus.DebugLevel) {
+		logObj.logger.Debugf(format, v...)
 	}
 }
 
@@ -228,8 +572,8 @@ func Info(v ...interface{}) {
 		fmt.Println(append([]interface{}{"[INFO]"}, v...)...)
 		return
 	}
-	if logObj.iWriter.IsLevelEnabled(logrus.InfoLevel) {
-		logObj.iWriter.Info(v...)
+	if logObj.logger.IsLevelEnabled(logrus.InfoLevel) {
+		logObj.logger.Info(v...)
 	}
 }
 
@@ -238,8 +582,8 @@ func Infof(format string, v ...interface{}) {
 		fmt.Printf("[INFO] "+format+"\n", v...)
 		return
 	}
-	if logObj.iWriter.IsLevelEnabled(logrus.InfoLevel) {
-		logObj.iWriter.Infof(format, v...)
+	if logObj.logger.IsLevelEnabled(logrus.InfoLevel) {
+		logObj.logger.Infof(format, v...)
 	}
 }
 
@@ -248,8 +592,8 @@ func Warn(v ...interface{}) {
 		fmt.Println(append([]interface{}{"[WARN]"}, v...)...)
 		return
 	}
-	if logObj.fWriter.IsLevelEnabled(logrus.WarnLevel) {
-		logObj.fWriter.Warn(v...)
+	if logObj.logger.IsLevelEnabled(logrus.WarnLevel) {
+		logObj.logger.Warn(v...)
 	}
 }
 
@@ -258,8 +602,8 @@ func Warnf(format string, v ...interface{}) {
 		fmt.Printf("[WARN] "+format+"\n", v...)
 		return
 	}
-	if logObj.fWriter.IsLevelEnabled(logrus.WarnLevel) {
-		logObj.fWriter.Warnf(format, v...)
+	if logObj.logger.IsLevelEnabled(logrus.WarnLevel) {
+		logObj.logger.Warnf(format, v...)
 	}
 }
 
@@ -268,8 +612,8 @@ func Error(v ...interface{}) {
 		fmt.Println(append([]interface{}{"[ERROR]"}, v...)...)
 		return
 	}
-	if logObj.fWriter.IsLevelEnabled(logrus.ErrorLevel) {
-		logObj.fWriter.Error(v...)
+	if logObj.logger.IsLevelEnabled(logrus.ErrorLevel) {
+		logObj.logger.Error(v...)
 	}
 }
 
@@ -278,8 +622,8 @@ func Errorf(format string, v ...interface{}) {
 		fmt.Printf("[ERROR] "+format+"\n", v...)
 		return
 	}
-	if logObj.fWriter.IsLevelEnabled(logrus.ErrorLevel) {
-		logObj.fWriter.Errorf(format, v...)
+	if logObj.logger.IsLevelEnabled(logrus.ErrorLevel) {
+		logObj.logger.Errorf(format, v...)
 	}
 }
 
@@ -371,8 +715,12 @@ func (f *textFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	var b *bytes.Buffer
 	keys := make([]string, 0, len(entry.Data))
 	for k := range entry.Data {
+		if k == KeyLogId || k == KeyRemoteAddr {
+			continue
+		}
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
 	entry.Message = strings.TrimSuffix(entry.Message, "\n")
 
@@ -386,9 +734,12 @@ func (f *textFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 		b = &bytes.Buffer{}
 	}
 
-	entry.Caller = getCaller()
-	funcVal := entry.Caller.Function
-	fileVal := fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line)
+	// 后台goroutine（例如采样的汇总）输出时没有业务的调用栈
+	funcVal, fileVal := "-", "-"
+	if entry.Caller = getCaller(); entry.Caller != nil {
+		funcVal = entry.Caller.Function
+		fileVal = fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line)
+	}
 
 	f.appendValue(b, strings.ToUpper(entry.Level.String()))
 	b.WriteByte(' ')
@@ -400,11 +751,40 @@ func (f *textFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	f.appendValue(b, fileVal)
 	b.WriteByte(' ')
 
+	if logId, ok := entry.Data[KeyLogId]; ok {
+		b.WriteByte('[')
+		f.appendValue(b, logId)
+		b.WriteString("] ")
+	}
+	if remoteAddr, ok := entry.Data[KeyRemoteAddr]; ok {
+		b.WriteString(KeyRemoteAddr)
+		b.WriteByte('=')
+		f.appendQuoted(b, remoteAddr)
+		b.WriteByte(' ')
+	}
 	f.appendValue(b, entry.Message)
+	for _, k := range keys {
+		b.WriteByte(' ')
+		b.WriteString(k)
+		b.WriteByte('=')
+		f.appendQuoted(b, entry.Data[k])
+	}
 	b.WriteByte('\n')
 	return b.Bytes(), nil
 }
 
+// appendQuoted 字段值中有空格、=、引号时加引号，保证k=v可以被切分
+func (f *textFormatter) appendQuoted(b *bytes.Buffer, value interface{}) {
+	stringVal, ok := value.(string)
+	if !ok {
+		stringVal = fmt.Sprint(value)
+	}
+	if strings.ContainsAny(stringVal, " =\"\n\t") || stringVal == "" {
+		stringVal = strconv.Quote(stringVal)
+	}
+	b.WriteString(stringVal)
+}
+
 func (f *textFormatter) appendValue(b *bytes.Buffer, value interface{}) {
 	stringVal, ok := value.(string)
 	if !ok {
@@ -417,6 +797,7 @@ func (f *textFormatter) appendValue(b *bytes.Buffer, value interface{}) {
  * toolbox/log toolbox/log/logrus_wrap github.com/sirupsen/logrus
  * 上述这三个包如果要打印 log，函数调用栈会不准确。
  * 理论上这仨库不需要打日志到业务日志文件中，打印到标准输出即可
+ * 标准库的log通过log.NewStdLogger转发时同样跳过，调用方是使用*log.Logger的代码
  */
 func getCaller() *runtime.Frame {
 	pcs := make([]uintptr, 25)
@@ -425,7 +806,7 @@ func getCaller() *runtime.Frame {
 
 	for f, again := frames.Next(); again; f, again = frames.Next() {
 		pkg := getPackageName(f.Function)
-		if !strings.Contains(pkg, "toolbox/log") && !strings.Contains(pkg, "sirupsen/logrus") {
+		if !strings.Contains(pkg, "toolbox/log") && !strings.Contains(pkg, "sirupsen/logrus") && pkg != "log" {
 			return &f
 		}
 	}
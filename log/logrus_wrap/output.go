@@ -0,0 +1,78 @@
+package logrus_wrap
+
+import (
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// entryWriter 写入格式化后的一行日志，level用于异步写入时的丢弃策略
+type entryWriter interface {
+	WriteEntry(level logrus.Level, p []byte) error
+	// Close 写完缓冲中的日志并关闭底层的writer
+	Close() error
+}
+
+// syncWriter 在调用方的goroutine中直接写入
+type syncWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *syncWriter) WriteEntry(level logrus.Level, p []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := w.writer.Write(p)
+	return err
+}
+
+func (w *syncWriter) Close() error {
+	if c, ok := w.writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// nopCloser 标准输出、标准错误不随Logger关闭
+type nopCloser struct {
+	io.Writer
+}
+
+// flusher 有缓冲的entryWriter，Flush等待缓冲中的日志写完
+type flusher interface {
+	Flush()
+}
+
+// dropCounter 会丢弃日志的entryWriter，按level统计丢弃的条数
+type dropCounter interface {
+	Dropped() map[string]uint64
+}
+
+// outputHook 日志的输出放在hook中完成，这样写入时能拿到entry的level，
+// logrus.Logger本身的Out为ioutil.Discard
+type outputHook struct {
+	formatter logrus.Formatter
+	writer    entryWriter
+	// levels 这个sink接收的level
+	levels []logrus.Level
+}
+
+func (h *outputHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *outputHook) Fire(entry *logrus.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	return h.writer.WriteEntry(entry.Level, b)
+}
+
+// nopFormatter logrus.Logger自身的输出被丢弃，不需要格式化
+type nopFormatter struct{}
+
+func (nopFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
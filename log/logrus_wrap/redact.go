@@ -0,0 +1,217 @@
+package logrus_wrap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zer0131/toolbox/base/util"
+)
+
+// 内置的脱敏规则
+const (
+	RedactMobile = "mobile"
+	RedactEmail  = "email"
+	RedactToken  = "token"
+	RedactCookie = "cookie"
+)
+
+const redactMask = "***"
+
+var allRedactDetectors = []string{RedactToken, RedactCookie, RedactEmail, RedactMobile}
+
+// defaultRedactKeys 结构化字段中值整体替换为***的key，文本中的key=value同样处理，不区分大小写
+var defaultRedactKeys = []string{
+	"password", "passwd", "pwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "set-cookie",
+}
+
+// RedactFunc 自定义的脱敏规则，key为结构化字段的key，消息本身key为空，返回脱敏后的value
+type RedactFunc func(key, value string) string
+
+var (
+	regexpRedactEmail  = regexp.MustCompile(util.EmailPattern)
+	regexpRedactMobile = regexp.MustCompile(util.MobilePattern)
+	regexpRedactBearer = regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9\-._~+/]+=*`)
+	// regexpRedactCookie 匹配Cookie: a=1; b=2、http_cookie=a=1; b=2
+	regexpRedactCookie     = regexp.MustCompile(`(?i)(cookie["']?\s*[=:]\s*"?)((?:[^\s=;"]+=[^\s;"]*(?:;\s?)?)+)`)
+	regexpRedactCookiePair = regexp.MustCompile(`([^\s=;"]+=)[^\s;"]*`)
+)
+
+type redactor struct {
+	detectors map[string]bool
+	keys      map[string]bool
+	keyValue  *regexp.Regexp
+	fn        RedactFunc
+}
+
+// newRedactor 没有开启脱敏时返回nil
+func newRedactor(opts logOptions) *redactor {
+	if !opts.redact {
+		return nil
+	}
+	detectors := opts.redactDetectors
+	if len(detectors) == 0 {
+		detectors = allRedactDetectors
+	}
+	r := &redactor{
+		detectors: make(map[string]bool, len(detectors)),
+		keys:      make(map[string]bool),
+		fn:        opts.redactFunc,
+	}
+	for _, d := range detectors {
+		r.detectors[d] = true
+	}
+	var textKeys []string
+	for _, k := range append(append([]string(nil), defaultRedactKeys...), opts.redactKeys...) {
+		k = strings.ToLower(k)
+		r.keys[k] = true
+		if !strings.Contains(k, "cookie") {
+			textKeys = append(textKeys, regexp.QuoteMeta(k))
+		}
+	}
+	// password=xxx、"token":"xxx"、Authorization: Bearer xxx
+	r.keyValue = regexp.MustCompile(`(?i)((?:` + strings.Join(textKeys, "|") + `)["']?\s*[=:]\s*["']?(?:(?:bearer|basic)\s+)?)[^\s"'&,;]+`)
+	return r
+}
+
+// redact 脱敏一段文本，nil redactor原样返回
+func (r *redactor) redact(s string) string {
+	if r == nil {
+		return s
+	}
+	s = r.redactText(s)
+	if r.fn != nil {
+		s = r.fn("", s)
+	}
+	return s
+}
+
+// redactText 内置规则
+func (r *redactor) redactText(s string) string {
+	if s == "" {
+		return s
+	}
+	if r.detectors[RedactToken] {
+		s = r.keyValue.ReplaceAllString(s, "${1}"+redactMask)
+		s = regexpRedactBearer.ReplaceAllString(s, "${1}"+redactMask)
+	}
+	if r.detectors[RedactCookie] {
+		s = regexpRedactCookie.ReplaceAllStringFunc(s, func(m string) string {
+			sub := regexpRedactCookie.FindStringSubmatch(m)
+			return sub[1] + regexpRedactCookiePair.ReplaceAllString(sub[2], "${1}"+redactMask)
+		})
+	}
+	if r.detectors[RedactEmail] {
+		s = regexpRedactEmail.ReplaceAllStringFunc(s, maskEmail)
+	}
+	if r.detectors[RedactMobile] {
+		s = redactMobile(s)
+	}
+	return s
+}
+
+// redactField 结构化字段，key命中时整体替换，字符串、error按文本脱敏，其他类型原样返回
+func (r *redactor) redactField(key string, value interface{}) interface{} {
+	if r.keys[strings.ToLower(key)] {
+		return redactMask
+	}
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		return value
+	}
+	out := r.redactText(s)
+	if r.fn != nil {
+		out = r.fn(key, out)
+	}
+	if out == s {
+		return value
+	}
+	return out
+}
+
+// redactMobile 前后是数字时不是手机号，例如毫秒时间戳、订单号
+func redactMobile(s string) string {
+	locs := regexpRedactMobile.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		if (loc[0] > 0 && isDigit(s[loc[0]-1])) || (loc[1] < len(s) && isDigit(s[loc[1]])) {
+			continue
+		}
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(maskMobile(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// maskMobile 保留前3位和后4位，例如138****5678
+func maskMobile(s string) string {
+	n := len(s)
+	return s[:n-8] + "****" + s[n-4:]
+}
+
+// maskEmail 保留第一个字符和域名，例如a***@example.com
+func maskEmail(s string) string {
+	i := strings.LastIndex(s, "@")
+	if i <= 0 {
+		return redactMask
+	}
+	return s[:1] + redactMask + s[i:]
+}
+
+// redactHook 在输出到sink的hook之前注册，所有sink写入的都是脱敏后的内容
+type redactHook struct {
+	redactor *redactor
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redactor.redact(entry.Message)
+	for k, v := range entry.Data {
+		if k == KeyLogId || k == KeyRemoteAddr {
+			continue
+		}
+		entry.Data[k] = h.redactor.redactField(k, v)
+	}
+	return nil
+}
+
+// defaultRedactor Redact在没有开启脱敏时使用，内置的全部规则
+var defaultRedactor = newRedactor(logOptions{redact: true})
+
+// Redact 按全局logger的脱敏配置处理文本，没有开启脱敏时使用内置的全部规则，
+// 用于不经过logrus_wrap输出的日志，例如accesslog
+func Redact(s string) string {
+	if logObj != nil && logObj.redactor != nil {
+		return logObj.redactor.redact(s)
+	}
+	return defaultRedactor.redact(s)
+}
+
+func (logObj *Logger) Redact(s string) string {
+	if logObj != nil && logObj.redactor != nil {
+		return logObj.redactor.redact(s)
+	}
+	return defaultRedactor.redact(s)
+}
@@ -0,0 +1,97 @@
+package logrus_wrap
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	r := newRedactor(logOptions{redact: true, redactKeys: []string{"id_card"}})
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"call 13800138000 failed", "call 138****8000 failed"},
+		{"mobile=+8613800138000", "mobile=+86138****8000"},
+		{"ts=1634567890123 order=213800138000", "ts=1634567890123 order=213800138000"},
+		{"user foo.bar@example.com login", "user f***@example.com login"},
+		{"password=abc123&name=foo", "password=***&name=foo"},
+		{`{"access_token":"xyz","id_card":"110101"}`, `{"access_token":"***","id_card":"***"}`},
+		{"Authorization: Bearer eyJhbGciOi.abc", "Authorization: Bearer ***"},
+		{"auth Bearer eyJhbGciOi.abc", "auth Bearer ***"},
+		{"http_cookie=sid=abc; uid=13800138000 http_user_agent=curl", "http_cookie=sid=***; uid=*** http_user_agent=curl"},
+		{"Cookie: a=1;b=2", "Cookie: a=***;b=***"},
+	}
+	for _, c := range cases {
+		if got := r.redact(c.in); got != c.want {
+			t.Errorf("redact(%s) = %s, want %s", c.in, got, c.want)
+		}
+	}
+
+	// 只使用部分内置规则
+	r = newRedactor(logOptions{redact: true, redactDetectors: []string{RedactEmail}})
+	if got := r.redact("foo@example.com 13800138000"); got != "f***@example.com 13800138000" {
+		t.Errorf("unexpected %s", got)
+	}
+
+	if newRedactor(logOptions{}) != nil {
+		t.Errorf("redactor enabled by default")
+	}
+}
+
+func TestRedactField(t *testing.T) {
+	r := newRedactor(logOptions{redact: true, redactFunc: func(key, value string) string {
+		if key == "name" {
+			return "<name>"
+		}
+		return strings.Replace(value, "internal", "<host>", -1)
+	}})
+	cases := []struct {
+		key   string
+		value interface{}
+		want  interface{}
+	}{
+		{"Password", "abc", redactMask},
+		{"phone", "13800138000", "138****8000"},
+		{"err", errors.New("send to foo@example.com failed"), "send to f***@example.com failed"},
+		{"count", 13800138000, 13800138000},
+		{"name", "foo", "<name>"},
+		{"host", "internal.example", "<host>.example"},
+	}
+	for _, c := range cases {
+		if got := r.redactField(c.key, c.value); got != c.want {
+			t.Errorf("redactField(%s, %v) = %v, want %v", c.key, c.value, got, c.want)
+		}
+	}
+	if got := r.redact("ping internal"); got != "ping <host>" {
+		t.Errorf("unexpected %s", got)
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithPath(dir), WithRedact(), WithSinks(
+		FileSink("app.log"),
+		FileSink("app.json", WithSinkFormat(FormatJSON)),
+	))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	logObj.Log(LevelInfo, Fields{KeyLogId: "13800138000", "token": "abc", "email": "foo@example.com"}, "sms to 13800138000")
+	_ = logObj.Close()
+
+	for _, name := range []string{"app.log", "app.json"} {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		content := string(b)
+		if strings.Contains(content, "sms to 13800138000") || strings.Contains(content, "abc") || strings.Contains(content, "foo@example.com") {
+			t.Errorf("%s not redacted:\n%s", name, content)
+		}
+		// log-id不脱敏
+		if !strings.Contains(content, "13800138000") {
+			t.Errorf("%s log-id redacted:\n%s", name, content)
+		}
+	}
+}
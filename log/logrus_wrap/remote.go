@@ -0,0 +1,243 @@
+package logrus_wrap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRemoteTimeout = 3 * time.Second
+	// defaultRemoteMaxPending 远端不可用时最多缓存的条数，超过时丢弃
+	defaultRemoteMaxPending = 8192
+)
+
+// sender 发送一批日志
+type sender interface {
+	Send(p []byte) error
+	Close() error
+}
+
+// batchWriter 缓存日志，每batchSize条或每interval由后台goroutine发送一次，
+// 发送失败的日志被丢弃并计数，不阻塞调用方
+type batchWriter struct {
+	sender     sender
+	batchSize  int
+	interval   time.Duration
+	maxPending int
+
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	count   int
+	levels  [logrus.TraceLevel + 1]uint64
+	dropped [logrus.TraceLevel + 1]uint64
+	closed  bool
+
+	notify  chan struct{}
+	flushCh chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newBatchWriter(s sender, batchSize int, interval time.Duration) *batchWriter {
+	if batchSize <= 0 {
+		batchSize = defaultSinkBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultSinkFlushInterval
+	}
+	maxPending := defaultRemoteMaxPending
+	if maxPending < batchSize {
+		maxPending = batchSize
+	}
+	w := &batchWriter{
+		sender:     s,
+		batchSize:  batchSize,
+		interval:   interval,
+		maxPending: maxPending,
+		notify:     make(chan struct{}, 1),
+		flushCh:    make(chan chan struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *batchWriter) WriteEntry(level logrus.Level, p []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed || w.count >= w.maxPending {
+		w.dropped[level]++
+		return nil
+	}
+	w.buf.Write(p)
+	w.count++
+	w.levels[level]++
+	if w.count >= w.batchSize {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (w *batchWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.send()
+		case <-w.notify:
+			w.send()
+		case ch := <-w.flushCh:
+			w.send()
+			close(ch)
+		case <-w.stop:
+			w.send()
+			return
+		}
+	}
+}
+
+// send 只在后台goroutine中调用
+func (w *batchWriter) send() {
+	w.mutex.Lock()
+	if w.count == 0 {
+		w.mutex.Unlock()
+		return
+	}
+	data := make([]byte, w.buf.Len())
+	copy(data, w.buf.Bytes())
+	levels := w.levels
+	w.buf.Reset()
+	w.count = 0
+	w.levels = [logrus.TraceLevel + 1]uint64{}
+	w.mutex.Unlock()
+
+	if err := w.sender.Send(data); err != nil {
+		fmt.Printf("[logrus] send log err: %s\n", err)
+		w.mutex.Lock()
+		for l, n := range levels {
+			w.dropped[l] += n
+		}
+		w.mutex.Unlock()
+	}
+}
+
+// Flush 等待缓存的日志发送完成
+func (w *batchWriter) Flush() {
+	ch := make(chan struct{})
+	select {
+	case w.flushCh <- ch:
+		<-ch
+	case <-w.done:
+	}
+}
+
+// Dropped 缓存满或发送失败丢弃的条数
+func (w *batchWriter) Dropped() map[string]uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	dropped := make(map[string]uint64)
+	for l, n := range w.dropped {
+		if n > 0 {
+			dropped[levelMapper[logrus.Level(l)]] += n
+		}
+	}
+	return dropped
+}
+
+// Close 发送缓存的日志后关闭连接，之后的日志被丢弃
+func (w *batchWriter) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mutex.Unlock()
+	close(w.stop)
+	<-w.done
+	return w.sender.Close()
+}
+
+// tcpSender 每条日志一行写入tcp连接，写入失败时重连一次
+type tcpSender struct {
+	addr string
+	conn net.Conn
+}
+
+func newTCPSender(addr string) *tcpSender {
+	return &tcpSender{addr: addr}
+}
+
+func (s *tcpSender) Send(p []byte) error {
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if s.conn, err = net.DialTimeout("tcp", s.addr, defaultRemoteTimeout); err != nil {
+				continue
+			}
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(defaultRemoteTimeout))
+		if _, err = s.conn.Write(p); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *tcpSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpSender 每批日志POST一次，非2xx的返回视为失败
+type httpSender struct {
+	url         string
+	contentType string
+	client      *http.Client
+}
+
+func newHTTPSender(url, contentType string) *httpSender {
+	return &httpSender{
+		url:         url,
+		contentType: contentType,
+		client:      &http.Client{Timeout: defaultRemoteTimeout},
+	}
+}
+
+func (s *httpSender) Send(p []byte) error {
+	resp, err := s.client.Post(s.url, s.contentType, bytes.NewReader(p))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("post %s status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
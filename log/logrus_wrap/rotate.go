@@ -0,0 +1,131 @@
+package logrus_wrap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/zer0131/toolbox/common"
+)
+
+const gzipSuffix = ".gz"
+
+// fileRetention 一个Logger的所有日志文件（例如.log和.log.wf）共用，
+// 切割后压缩旧文件，并按文件个数和总大小清理，expireDay仍然由rotatelogs处理
+type fileRetention struct {
+	mutex        sync.Mutex
+	dir          string
+	compress     bool
+	maxFiles     int
+	maxTotalSize int64
+	files        []*retentionFile
+}
+
+type retentionFile struct {
+	// pattern 匹配切割后的文件，例如app.log.2021010112、app.log.2021010112.1、app.log.2021010112.gz
+	pattern *regexp.Regexp
+	writer  *rotatelogs.RotateLogs
+}
+
+func newFileRetention(dir string, opts logOptions) *fileRetention {
+	if !opts.compress && opts.maxFiles <= 0 && opts.maxTotalSize <= 0 {
+		return nil
+	}
+	return &fileRetention{
+		dir:          dir,
+		compress:     opts.compress,
+		maxFiles:     opts.maxFiles,
+		maxTotalSize: opts.maxTotalSize,
+	}
+}
+
+func (r *fileRetention) add(fileName string, writer *rotatelogs.RotateLogs) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.files = append(r.files, &retentionFile{
+		pattern: regexp.MustCompile(`^` + regexp.QuoteMeta(fileName) + `\.\d{10}(\.\d+)?(` + regexp.QuoteMeta(gzipSuffix) + `)?$`),
+		writer:  writer,
+	})
+}
+
+// Handle rotatelogs在切割时回调（在单独的goroutine中）
+func (r *fileRetention) Handle(rotatelogs.Event) {
+	r.cleanup()
+}
+
+type retentionEntry struct {
+	path    string
+	size    int64
+	modTime int64
+	current bool
+}
+
+func (r *fileRetention) cleanup() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	infos, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		fmt.Printf("[logrus] read log dir err: %s\n", err)
+		return
+	}
+	current := make(map[string]bool, len(r.files))
+	for _, f := range r.files {
+		current[filepath.Base(f.writer.CurrentFileName())] = true
+	}
+
+	var (
+		rotated []retentionEntry
+		total   int64
+	)
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || !r.match(info.Name()) {
+			continue
+		}
+		e := retentionEntry{
+			path:    filepath.Join(r.dir, info.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime().UnixNano(),
+			current: current[info.Name()],
+		}
+		if !e.current && r.compress && !strings.HasSuffix(e.path, gzipSuffix) {
+			if err := common.GzipFile(e.path, e.path+gzipSuffix); err != nil {
+				fmt.Printf("[logrus] gzip %s err: %s\n", e.path, err)
+			} else if gz, err := os.Stat(e.path + gzipSuffix); err == nil {
+				// 保留原来的修改时间，清理时按时间排序
+				_ = os.Chtimes(e.path+gzipSuffix, info.ModTime(), info.ModTime())
+				e.path += gzipSuffix
+				e.size = gz.Size()
+			}
+		}
+		total += e.size
+		if !e.current {
+			rotated = append(rotated, e)
+		}
+	}
+
+	// 从最旧的开始删除，正在写入的文件不删除
+	sort.Slice(rotated, func(i, j int) bool { return rotated[i].modTime < rotated[j].modTime })
+	for len(rotated) > 0 && ((r.maxFiles > 0 && len(rotated) > r.maxFiles) || (r.maxTotalSize > 0 && total > r.maxTotalSize)) {
+		if err := os.Remove(rotated[0].path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("[logrus] remove %s err: %s\n", rotated[0].path, err)
+		}
+		total -= rotated[0].size
+		rotated = rotated[1:]
+	}
+}
+
+func (r *fileRetention) match(name string) bool {
+	for _, f := range r.files {
+		if f.pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package logrus_wrap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	retention := newFileRetention(dir, logOptions{compress: true, maxFiles: 3})
+	for _, name := range []string{"app.log", "app.log.wf"} {
+		writer, err := newFileWriter(logOptions{expireDay: 7}, dir, name, retention)
+		if err != nil {
+			t.Fatalf("new writer err: %s", err)
+		}
+		if _, err := writer.Write([]byte("current\n")); err != nil {
+			t.Fatalf("write err: %s", err)
+		}
+	}
+
+	// 切割后的旧文件，越往后越新
+	old := []string{"app.log.2021010100", "app.log.wf.2021010101", "app.log.2021010102.1", "app.log.2021010103", "app.log.2021010104.gz"}
+	for i, name := range old {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte("rotated\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mt := time.Now().Add(time.Duration(i-len(old)) * time.Hour)
+		_ = os.Chtimes(p, mt, mt)
+	}
+	// 不属于这个Logger的文件不处理
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.log.2021010100"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	retention.cleanup()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rotated []string
+	for _, info := range infos {
+		if retention.match(info.Name()) && info.Mode().IsRegular() {
+			rotated = append(rotated, info.Name())
+		}
+	}
+	sort.Strings(rotated)
+	var want []string
+	for _, f := range retention.files {
+		want = append(want, filepath.Base(f.writer.CurrentFileName()))
+	}
+	want = append(want, "app.log.2021010102.1.gz", "app.log.2021010103.gz", "app.log.2021010104.gz")
+	sort.Strings(want)
+	if len(rotated) != len(want) {
+		t.Fatalf("unexpected files %v, want %v", rotated, want)
+	}
+	for i := range want {
+		if rotated[i] != want[i] {
+			t.Errorf("unexpected files %v, want %v", rotated, want)
+			break
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.log.2021010100")); err != nil {
+		t.Errorf("other file removed: %s", err)
+	}
+}
+
+func TestFileRetentionTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	retention := newFileRetention(dir, logOptions{maxTotalSize: 25})
+	if _, err := newFileWriter(logOptions{expireDay: 7}, dir, "app.log", retention); err != nil {
+		t.Fatalf("new writer err: %s", err)
+	}
+	for i, name := range []string{"app.log.2021010100", "app.log.2021010101", "app.log.2021010102"} {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mt := time.Now().Add(time.Duration(i-3) * time.Hour)
+		_ = os.Chtimes(p, mt, mt)
+	}
+
+	retention.cleanup()
+
+	if _, err := os.Stat(filepath.Join(dir, "app.log.2021010100")); !os.IsNotExist(err) {
+		t.Errorf("oldest file not removed")
+	}
+	for _, name := range []string{"app.log.2021010101", "app.log.2021010102"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s removed: %s", name, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package logrus_wrap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultSamplingInterval = time.Second
+
+// samplingRule 每个interval内同一个模板的日志先输出first条，之后每thereafter条输出1条，
+// thereafter<=0时之后的都不输出
+type samplingRule struct {
+	level      string
+	interval   time.Duration
+	first      int
+	thereafter int
+}
+
+type sampleKey struct {
+	level    logrus.Level
+	template string
+}
+
+type sampleCount struct {
+	start      time.Time
+	n          uint64
+	suppressed uint64
+}
+
+// sampler 按level和消息模板（Logf的format，Log的第一个字符串参数）采样，
+// 后台定期把窗口结束的计数清零，并输出被丢弃的条数
+type sampler struct {
+	rules [logrus.TraceLevel + 1]*samplingRule
+	emit  func(level logrus.Level, msg string)
+
+	mutex  sync.Mutex
+	counts map[sampleKey]*sampleCount
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// newSampler 没有配置规则时返回nil，nil sampler不做采样
+func newSampler(rules []samplingRule, emit func(level logrus.Level, msg string)) *sampler {
+	if len(rules) == 0 {
+		return nil
+	}
+	s := &sampler{
+		emit:   emit,
+		counts: make(map[sampleKey]*sampleCount),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	tick := time.Duration(0)
+	for i := range rules {
+		rule := rules[i]
+		l, ok := levelMapperRev[rule.level]
+		if !ok {
+			fmt.Printf("[logrus] unknown sampling level %s, ignored\n", rule.level)
+			continue
+		}
+		if rule.interval <= 0 {
+			rule.interval = defaultSamplingInterval
+		}
+		if rule.first < 0 {
+			rule.first = 0
+		}
+		s.rules[l] = &rule
+		if tick == 0 || rule.interval < tick {
+			tick = rule.interval
+		}
+	}
+	if tick == 0 {
+		return nil
+	}
+	go s.run(tick)
+	return s
+}
+
+// allow 返回false时这条日志被丢弃
+func (s *sampler) allow(level logrus.Level, template string) bool {
+	if s == nil || s.rules[level] == nil {
+		return true
+	}
+	rule := s.rules[level]
+	key := sampleKey{level: level, template: template}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		// Close之后不再有汇总输出，不做采样
+		return true
+	}
+	c, ok := s.counts[key]
+	if !ok {
+		c = &sampleCount{start: time.Now()}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= uint64(rule.first) {
+		return true
+	}
+	if rule.thereafter > 0 && (c.n-uint64(rule.first))%uint64(rule.thereafter) == 0 {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+func (s *sampler) run(tick time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.flush(now, false)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flush 清理窗口已结束的计数，all为true时清理全部，被丢弃的条数在锁外输出
+func (s *sampler) flush(now time.Time, all bool) {
+	type summary struct {
+		key        sampleKey
+		suppressed uint64
+	}
+	var summaries []summary
+
+	s.mutex.Lock()
+	for key, c := range s.counts {
+		if !all && now.Sub(c.start) < s.rules[key.level].interval {
+			continue
+		}
+		if c.suppressed > 0 {
+			summaries = append(summaries, summary{key: key, suppressed: c.suppressed})
+		}
+		delete(s.counts, key)
+	}
+	s.mutex.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].key.level != summaries[j].key.level {
+			return summaries[i].key.level < summaries[j].key.level
+		}
+		return summaries[i].key.template < summaries[j].key.template
+	})
+	for _, sum := range summaries {
+		s.emit(sum.key.level, fmt.Sprintf("suppressed %d messages: %s", sum.suppressed, sum.key.template))
+	}
+}
+
+// Close 停止后台goroutine并输出剩余的丢弃条数
+func (s *sampler) Close() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.mutex.Lock()
+		s.closed = true
+		s.mutex.Unlock()
+		s.flush(time.Now(), true)
+	})
+}
+
+// logTemplate Log的模板，第一个参数是字符串时使用它，否则使用整条消息
+func logTemplate(v []interface{}) string {
+	if len(v) > 0 {
+		if s, ok := v[0].(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprint(v...)
+}
@@ -0,0 +1,85 @@
+package logrus_wrap
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSampler(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		msgs  []string
+	)
+	s := newSampler([]samplingRule{{level: LevelError, interval: time.Hour, first: 3, thereafter: 10}}, func(level logrus.Level, msg string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		msgs = append(msgs, levelMapper[level]+" "+msg)
+	})
+
+	allowed := 0
+	for i := 0; i < 103; i++ {
+		if s.allow(logrus.ErrorLevel, "db err %s") {
+			allowed++
+		}
+	}
+	// 前3条，之后每10条1条
+	if allowed != 3+10 {
+		t.Errorf("unexpected allowed %d", allowed)
+	}
+	// 不同的模板分别计数
+	if !s.allow(logrus.ErrorLevel, "other %s") {
+		t.Errorf("other template sampled")
+	}
+	// 没有配置的level不采样
+	for i := 0; i < 100; i++ {
+		if !s.allow(logrus.InfoLevel, "db err %s") {
+			t.Fatalf("info sampled")
+		}
+	}
+
+	s.Close()
+	if len(msgs) != 1 || msgs[0] != "ERROR suppressed 90 messages: db err %s" {
+		t.Errorf("unexpected summary %v", msgs)
+	}
+	// Close之后不再采样
+	if !s.allow(logrus.ErrorLevel, "db err %s") {
+		t.Errorf("sampled after close")
+	}
+}
+
+func TestSamplerInterval(t *testing.T) {
+	summary := make(chan string, 10)
+	s := newSampler([]samplingRule{{level: LevelWarn, interval: 20 * time.Millisecond, first: 1}}, func(level logrus.Level, msg string) {
+		summary <- msg
+	})
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		s.allow(logrus.WarnLevel, "slow")
+	}
+	select {
+	case msg := <-summary:
+		if msg != "suppressed 4 messages: slow" {
+			t.Errorf("unexpected summary %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no summary")
+	}
+	// 窗口结束后重新计数
+	if !s.allow(logrus.WarnLevel, "slow") {
+		t.Errorf("sampled in new window")
+	}
+}
+
+func TestLogTemplate(t *testing.T) {
+	if v := logTemplate([]interface{}{"get user failed", 123}); v != "get user failed" {
+		t.Errorf("unexpected template %s", v)
+	}
+	if v := logTemplate([]interface{}{123, "abc"}); !strings.Contains(v, "123") {
+		t.Errorf("unexpected template %s", v)
+	}
+}
@@ -0,0 +1,189 @@
+package logrus_wrap
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sinkFile   = "file"
+	sinkStdout = "stdout"
+	sinkStderr = "stderr"
+	sinkSyslog = "syslog"
+	sinkTCP    = "tcp"
+	sinkHTTP   = "http"
+)
+
+const (
+	defaultSinkBatchSize     = 100
+	defaultSinkFlushInterval = time.Second
+)
+
+// Sink 日志的一个输出，每个sink有自己的level范围和格式，通过FileSink、StdoutSink等创建
+type Sink struct {
+	kind string
+	// target 文件名、地址或url
+	target  string
+	network string
+	tag     string
+	// level、maxLevel 接收的level范围，maxLevel为空时不限制
+	level    string
+	maxLevel string
+	// format 为空时使用Logger的格式
+	format        string
+	batchSize     int
+	flushInterval time.Duration
+}
+
+type SinkOptionsFunc func(*Sink)
+
+// WithSinkLevel 接收的最低level，默认DEBUG
+func WithSinkLevel(level string) SinkOptionsFunc {
+	return func(s *Sink) {
+		s.level = level
+	}
+}
+
+// WithSinkMaxLevel 接收的最高level，例如默认的.log只接收DEBUG、INFO
+func WithSinkMaxLevel(level string) SinkOptionsFunc {
+	return func(s *Sink) {
+		s.maxLevel = level
+	}
+}
+
+// WithSinkFormat FormatText或FormatJSON，默认与Logger的格式相同
+func WithSinkFormat(format string) SinkOptionsFunc {
+	return func(s *Sink) {
+		s.format = format
+	}
+}
+
+// WithSinkBatch TCPSink、HTTPSink每size条或每interval发送一次
+func WithSinkBatch(size int, interval time.Duration) SinkOptionsFunc {
+	return func(s *Sink) {
+		s.batchSize = size
+		s.flushInterval = interval
+	}
+}
+
+func newSink(kind, target string, opt []SinkOptionsFunc) Sink {
+	s := Sink{
+		kind:          kind,
+		target:        target,
+		level:         LevelDebug,
+		batchSize:     defaultSinkBatchSize,
+		flushInterval: defaultSinkFlushInterval,
+	}
+	for _, o := range opt {
+		o(&s)
+	}
+	return s
+}
+
+// FileSink 写入WithPath目录下的fileName，按小时切割，切割、压缩、清理的配置对所有文件生效
+func FileSink(fileName string, opt ...SinkOptionsFunc) Sink {
+	return newSink(sinkFile, fileName, opt)
+}
+
+func StdoutSink(opt ...SinkOptionsFunc) Sink {
+	return newSink(sinkStdout, "", opt)
+}
+
+func StderrSink(opt ...SinkOptionsFunc) Sink {
+	return newSink(sinkStderr, "", opt)
+}
+
+// SyslogSink 发送到syslog，network为unixgram、unix时addr为socket路径（例如/dev/log），
+// 为udp、tcp时addr为host:port，network和addr都为空时使用本机的syslog，level对应syslog的priority，
+// 与文件一样在配置WithAsync时异步写入
+func SyslogSink(network, addr, tag string, opt ...SinkOptionsFunc) Sink {
+	s := newSink(sinkSyslog, addr, opt)
+	s.network = network
+	s.tag = tag
+	return s
+}
+
+// TCPSink 批量发送到addr，每条日志一行，连接断开时重连
+func TCPSink(addr string, opt ...SinkOptionsFunc) Sink {
+	return newSink(sinkTCP, addr, opt)
+}
+
+// HTTPSink 批量POST到url，body为多行日志
+func HTTPSink(url string, opt ...SinkOptionsFunc) Sink {
+	return newSink(sinkHTTP, url, opt)
+}
+
+// WithSinks 日志的输出，替换默认的文件：NewLogger默认为.log（DEBUG、INFO）和.log.wf（WARN、ERROR），
+// NewCustomLogger默认为.log
+func WithSinks(sinks ...Sink) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.sinks = append([]Sink(nil), sinks...)
+	}
+}
+
+// levels sink接收的level，logrus的level越小越严重
+func (s Sink) levels() []logrus.Level {
+	min := parseSinkLevel(s.level, logrus.DebugLevel)
+	max := parseSinkLevel(s.maxLevel, logrus.PanicLevel)
+	var levels []logrus.Level
+	for _, l := range logrus.AllLevels {
+		if l <= min && l >= max {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func parseSinkLevel(level string, def logrus.Level) logrus.Level {
+	if level == "" {
+		return def
+	}
+	if v, ok := levelMapperRev[level]; ok {
+		return v
+	}
+	fmt.Printf("[logrus] unknown sink level %s, now using %s\n", level, levelMapper[def])
+	return def
+}
+
+func newSinkWriter(opts logOptions, sink Sink, dir string, retention *fileRetention) (entryWriter, error) {
+	switch sink.kind {
+	case sinkFile:
+		writer, err := newFileWriter(opts, dir, sink.target, retention)
+		if err != nil {
+			return nil, err
+		}
+		return withAsync(opts, &syncWriter{writer: writer}), nil
+	case sinkStdout, sinkStderr:
+		writer := nopCloser{os.Stdout}
+		if sink.kind == sinkStderr {
+			writer = nopCloser{os.Stderr}
+		}
+		return withAsync(opts, &syncWriter{writer: writer}), nil
+	case sinkSyslog:
+		writer, err := newSyslogWriter(sink.network, sink.target, sink.tag)
+		if err != nil {
+			return nil, err
+		}
+		return withAsync(opts, writer), nil
+	case sinkTCP:
+		return newBatchWriter(newTCPSender(sink.target), sink.batchSize, sink.flushInterval), nil
+	case sinkHTTP:
+		contentType := "text/plain; charset=utf-8"
+		if sink.format == FormatJSON || (sink.format == "" && opts.format == FormatJSON) {
+			contentType = "application/x-ndjson"
+		}
+		return newBatchWriter(newHTTPSender(sink.target, contentType), sink.batchSize, sink.flushInterval), nil
+	}
+	return nil, fmt.Errorf("unknown sink %s", sink.kind)
+}
+
+// withAsync 配置了WithAsync时由后台goroutine写入，不在logrus.Logger的锁中等待IO
+func withAsync(opts logOptions, writer entryWriter) entryWriter {
+	if opts.asyncSize > 0 {
+		return newAsyncWriter(writer, opts.asyncSize, opts.overflow)
+	}
+	return writer
+}
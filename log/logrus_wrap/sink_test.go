@@ -0,0 +1,188 @@
+package logrus_wrap
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSinkLevels(t *testing.T) {
+	cases := []struct {
+		sink Sink
+		want []logrus.Level
+	}{
+		{FileSink("app.log", WithSinkMaxLevel(LevelInfo)), []logrus.Level{logrus.InfoLevel, logrus.DebugLevel}},
+		{FileSink("app.log.wf", WithSinkLevel(LevelWarn)), []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}},
+		{StdoutSink(WithSinkLevel(LevelInfo), WithSinkMaxLevel(LevelWarn)), []logrus.Level{logrus.WarnLevel, logrus.InfoLevel}},
+	}
+	for _, c := range cases {
+		got := c.sink.levels()
+		if len(got) != len(c.want) {
+			t.Errorf("unexpected levels %v, want %v", got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("unexpected levels %v, want %v", got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFileSinks(t *testing.T) {
+	dir := t.TempDir()
+	logObj, err := NewCustomLogger(WithPath(dir), WithSinks(
+		FileSink("app.log"),
+		FileSink("audit.log", WithSinkLevel(LevelWarn), WithSinkFormat(FormatJSON)),
+	))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	logObj.Log(LevelInfo, nil, "info line")
+	logObj.Log(LevelError, nil, "error line")
+	_ = logObj.Close()
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if !strings.Contains(string(b), "info line") || !strings.Contains(string(b), "error line") {
+		t.Errorf("unexpected app.log:\n%s", b)
+	}
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	if strings.Contains(string(b), "info line") || !strings.Contains(string(b), `"msg":"error line"`) {
+		t.Errorf("unexpected audit.log:\n%s", b)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		mutex  sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		bodies = append(bodies, r.Header.Get("Content-Type")+"\n"+string(b))
+	}))
+	defer srv.Close()
+
+	logObj, err := NewCustomLogger(WithFormat(FormatJSON), WithSinks(HTTPSink(srv.URL, WithSinkBatch(2, time.Hour))))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		logObj.Logf(LevelInfo, nil, "line %d", i)
+	}
+	logObj.Flush()
+	_ = logObj.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	all := strings.Join(bodies, "")
+	if strings.Count(all, `"msg":"line`) != 3 || !strings.HasPrefix(all, "application/x-ndjson") {
+		t.Errorf("unexpected bodies %q", bodies)
+	}
+	if len(logObj.Dropped()) != 0 {
+		t.Errorf("unexpected dropped %v", logObj.Dropped())
+	}
+}
+
+func TestTCPSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	logObj, err := NewCustomLogger(WithSinks(TCPSink(ln.Addr().String(), WithSinkLevel(LevelWarn))))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	logObj.Log(LevelInfo, nil, "info line")
+	logObj.Log(LevelWarn, nil, "warn line")
+	_ = logObj.Close()
+
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, "warn line") {
+			t.Errorf("unexpected line %s", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no line received")
+	}
+}
+
+func TestRemoteSinkDropped(t *testing.T) {
+	// 没有监听的端口，发送失败后计入Dropped
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	logObj, err := NewCustomLogger(WithSinks(TCPSink(addr)))
+	if err != nil {
+		t.Fatalf("new custom logger err: %s", err)
+	}
+	logObj.Log(LevelError, nil, "lost")
+	_ = logObj.Close()
+	if n := logObj.Dropped()[LevelError]; n != 1 {
+		t.Errorf("unexpected dropped %v", logObj.Dropped())
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("syslog is not supported on windows")
+	}
+	addr := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("listen unixgram err: %s", err)
+	}
+	defer conn.Close()
+
+	// 同步与WithAsync都按level使用syslog的priority
+	for _, opt := range []LogOptionsFunc{WithAsync(0, ""), WithAsync(16, OverflowBlock)} {
+		logObj, err := NewCustomLogger(opt, WithSinks(SyslogSink("unixgram", addr, "toolbox")))
+		if err != nil {
+			t.Fatalf("new custom logger err: %s", err)
+		}
+		logObj.Log(LevelWarn, nil, "syslog line")
+
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		_ = logObj.Close()
+		if err != nil {
+			t.Fatalf("read err: %s", err)
+		}
+		// <12>: LOG_USER|LOG_WARNING
+		if msg := string(buf[:n]); !strings.HasPrefix(msg, "<12>") || !strings.Contains(msg, "toolbox") || !strings.Contains(msg, "syslog line") {
+			t.Errorf("unexpected message %s", msg)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+// +build !windows
+
+package logrus_wrap
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syslogWriter 按level使用syslog的priority，syslog.Writer断开时会自动重连
+type syslogWriter struct {
+	writer *syslog.Writer
+}
+
+func newSyslogWriter(network, addr, tag string) (entryWriter, error) {
+	writer, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		fmt.Printf("[logrus] failed to dial syslog: %s\n", err)
+		return nil, err
+	}
+	return &syslogWriter{writer: writer}, nil
+}
+
+func (w *syslogWriter) WriteEntry(level logrus.Level, p []byte) error {
+	msg := string(p)
+	switch {
+	case level <= logrus.ErrorLevel:
+		return w.writer.Err(msg)
+	case level == logrus.WarnLevel:
+		return w.writer.Warning(msg)
+	case level == logrus.InfoLevel:
+		return w.writer.Info(msg)
+	default:
+		return w.writer.Debug(msg)
+	}
+}
+
+func (w *syslogWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,8 @@
+package logrus_wrap
+
+import "errors"
+
+// newSyslogWriter windows没有log/syslog
+func newSyslogWriter(network, addr, tag string) (entryWriter, error) {
+	return nil, errors.New("syslog is not supported on windows")
+}
@@ -0,0 +1,40 @@
+package log
+
+import "github.com/zer0131/toolbox/log/logrus_wrap"
+
+// RedactFunc 自定义的脱敏规则，key为结构化字段的key，消息本身key为空，返回脱敏后的value
+type RedactFunc = logrus_wrap.RedactFunc
+
+// WithRedact 开启脱敏，在输出到任何sink之前处理消息和结构化字段。detectors为使用的内置规则：
+// RedactMobile（138****5678）、RedactEmail（a***@example.com）、RedactToken（password=***、Bearer ***）、
+// RedactCookie（Cookie: sid=***），为空时使用全部规则
+func WithRedact(detectors ...string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = append(o.redact[:len(o.redact):len(o.redact)], logrus_wrap.WithRedact(detectors...))
+	}
+}
+
+// WithRedactKeys 追加结构化字段中整体替换为***的key，不区分大小写，默认包括password、token、cookie等，
+// 同时开启脱敏
+func WithRedactKeys(keys ...string) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = append(o.redact[:len(o.redact):len(o.redact)], logrus_wrap.WithRedactKeys(keys...))
+	}
+}
+
+// WithRedactFunc 自定义的脱敏规则，在内置规则之后执行，同时开启脱敏
+func WithRedactFunc(fn RedactFunc) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.redact = append(o.redact[:len(o.redact):len(o.redact)], logrus_wrap.WithRedactFunc(fn))
+	}
+}
+
+// Redact 按全局logger的脱敏配置处理文本，没有开启脱敏时使用内置的全部规则，
+// 用于不经过log输出的日志，例如accesslog
+func Redact(s string) string {
+	return logrus_wrap.Redact(s)
+}
+
+func (logObj *Logger) Redact(s string) string {
+	return logObj.logger.Redact(s)
+}
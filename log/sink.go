@@ -0,0 +1,71 @@
+package log
+
+import (
+	"time"
+
+	"github.com/zer0131/toolbox/log/logrus_wrap"
+)
+
+// Sink 日志的一个输出，每个sink有自己的level范围和格式
+type Sink = logrus_wrap.Sink
+
+type SinkOptionsFunc = logrus_wrap.SinkOptionsFunc
+
+// WithSinks 日志的输出，替换默认的文件：InitV4默认为.log（DEBUG、INFO）和.log.wf（WARN、ERROR），
+// NewCustomLogger默认为.log。例如容器中输出json到标准输出，同时把WARN以上写入文件：
+//
+//	WithSinks(StdoutSink(WithSinkFormat(FormatJSON)), FileSink("app.log.wf", WithSinkLevel(LevelWarn)))
+func WithSinks(sinks ...Sink) LogOptionsFunc {
+	return func(o *logOptions) {
+		o.sinks = append([]Sink(nil), sinks...)
+	}
+}
+
+// FileSink 写入WithPath目录下的fileName，按小时切割，切割、压缩、清理的配置对所有文件生效
+func FileSink(fileName string, opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.FileSink(fileName, opt...)
+}
+
+func StdoutSink(opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.StdoutSink(opt...)
+}
+
+func StderrSink(opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.StderrSink(opt...)
+}
+
+// SyslogSink 发送到syslog，network为unixgram、unix时addr为socket路径（例如/dev/log），
+// 为udp、tcp时addr为host:port
+func SyslogSink(network, addr, tag string, opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.SyslogSink(network, addr, tag, opt...)
+}
+
+// TCPSink 批量发送到addr，每条日志一行
+func TCPSink(addr string, opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.TCPSink(addr, opt...)
+}
+
+// HTTPSink 批量POST到url，body为多行日志
+func HTTPSink(url string, opt ...SinkOptionsFunc) Sink {
+	return logrus_wrap.HTTPSink(url, opt...)
+}
+
+// WithSinkLevel 接收的最低level，默认DEBUG
+func WithSinkLevel(level string) SinkOptionsFunc {
+	return logrus_wrap.WithSinkLevel(level)
+}
+
+// WithSinkMaxLevel 接收的最高level
+func WithSinkMaxLevel(level string) SinkOptionsFunc {
+	return logrus_wrap.WithSinkMaxLevel(level)
+}
+
+// WithSinkFormat FormatText或FormatJSON，默认与WithFormat相同
+func WithSinkFormat(format string) SinkOptionsFunc {
+	return logrus_wrap.WithSinkFormat(format)
+}
+
+// WithSinkBatch TCPSink、HTTPSink每size条或每interval发送一次，默认100条、1秒
+func WithSinkBatch(size int, interval time.Duration) SinkOptionsFunc {
+	return logrus_wrap.WithSinkBatch(size, interval)
+}
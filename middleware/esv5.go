@@ -10,17 +10,16 @@ package middleware
 import (
 	"context"
 	"errors"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/olivere/elastic.v5"
 
-	"github.com/zer0131/toolbox"
+	"github.com/zer0131/toolbox/log"
 	"github.com/zer0131/toolbox/stat"
 )
 
@@ -249,506 +248,338 @@ type DpESV5 interface {
 }
 
 func (esv5 *ESV5) String() string {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.String()
 }
 
 func (esv5 *ESV5) IsRunning() bool {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IsRunning()
 }
 
 func (esv5 *ESV5) Start() {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	esv5.Client.Start()
 }
 
 func (esv5 *ESV5) Stop() {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	esv5.Client.Stop()
 }
 
 func (esv5 *ESV5) PerformRequest(ctx context.Context, method string, path string, params url.Values, body interface{}, ignoreErrors ...int) (*elastic.Response, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.PerformRequest(ctx, method, path, params, body, ignoreErrors...)
 }
 
 func (esv5 *ESV5) PerformRequestWithContentType(ctx context.Context, method string, path string, params url.Values, body interface{}, contentType string, ignoreErrors ...int) (*elastic.Response, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.PerformRequestWithContentType(ctx, method, path, params, body, contentType, ignoreErrors...)
 }
 
 func (esv5 *ESV5) PerformRequestWithOptions(ctx context.Context, opt elastic.PerformRequestOptions) (*elastic.Response, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.PerformRequestWithOptions(ctx, opt)
 }
 
 func (esv5 *ESV5) Index() *elastic.IndexService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Index()
 }
 
 func (esv5 *ESV5) Get() *elastic.GetService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Get()
 }
 
 func (esv5 *ESV5) MultiGet() *elastic.MgetService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.MultiGet()
 }
 
 func (esv5 *ESV5) Mget() *elastic.MgetService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Mget()
 }
 
 func (esv5 *ESV5) Delete() *elastic.DeleteService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Delete()
 }
 
 func (esv5 *ESV5) DeleteByQuery(indices ...string) *elastic.DeleteByQueryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.DeleteByQuery(indices...)
 }
 
 func (esv5 *ESV5) Update() *elastic.UpdateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Update()
 }
 
 func (esv5 *ESV5) UpdateByQuery(indices ...string) *elastic.UpdateByQueryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.UpdateByQuery(indices...)
 }
 
 func (esv5 *ESV5) Bulk() *elastic.BulkService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Bulk()
 }
 
 func (esv5 *ESV5) BulkProcessor() *elastic.BulkProcessorService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.BulkProcessor()
 }
 
 func (esv5 *ESV5) Reindex() *elastic.ReindexService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Reindex()
 }
 
 func (esv5 *ESV5) TermVectors(index string, typ string) *elastic.TermvectorsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.TermVectors(index, typ)
 }
 
 func (esv5 *ESV5) MultiTermVectors() *elastic.MultiTermvectorService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.MultiTermVectors()
 }
 
 func (esv5 *ESV5) Search(indices ...string) *elastic.SearchService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Search(indices...)
 }
 
 func (esv5 *ESV5) Suggest(indices ...string) *elastic.SuggestService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Suggest(indices...)
 }
 
 func (esv5 *ESV5) MultiSearch() *elastic.MultiSearchService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.MultiSearch()
 }
 
 func (esv5 *ESV5) Count(indices ...string) *elastic.CountService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Count(indices...)
 }
 
 func (esv5 *ESV5) Explain(index string, typ string, id string) *elastic.ExplainService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Explain(index, typ, id)
 }
 
 func (esv5 *ESV5) Validate(indices ...string) *elastic.ValidateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Validate(indices...)
 }
 
 func (esv5 *ESV5) SearchShards(indices ...string) *elastic.SearchShardsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SearchShards(indices...)
 }
 
 func (esv5 *ESV5) FieldCaps(indices ...string) *elastic.FieldCapsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.FieldCaps(indices...)
 }
 
 func (esv5 *ESV5) FieldStats(indices ...string) *elastic.FieldStatsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.FieldStats(indices...)
 }
 
 func (esv5 *ESV5) Exists() *elastic.ExistsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Exists()
 }
 
 func (esv5 *ESV5) Scroll(indices ...string) *elastic.ScrollService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Scroll(indices...)
 }
 
 func (esv5 *ESV5) ClearScroll(scrollIds ...string) *elastic.ClearScrollService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ClearScroll(scrollIds...)
 }
 
 func (esv5 *ESV5) CreateIndex(name string) *elastic.IndicesCreateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.CreateIndex(name)
 }
 
 func (esv5 *ESV5) DeleteIndex(indices ...string) *elastic.IndicesDeleteService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.DeleteIndex(indices...)
 }
 
 func (esv5 *ESV5) IndexExists(indices ...string) *elastic.IndicesExistsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexExists(indices...)
 }
 
 func (esv5 *ESV5) ShrinkIndex(source string, target string) *elastic.IndicesShrinkService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ShrinkIndex(source, target)
 }
 
 func (esv5 *ESV5) RolloverIndex(alias string) *elastic.IndicesRolloverService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.RolloverIndex(alias)
 }
 
 func (esv5 *ESV5) TypeExists() *elastic.IndicesExistsTypeService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.TypeExists()
 }
 
 func (esv5 *ESV5) IndexStats(indices ...string) *elastic.IndicesStatsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexStats(indices...)
 }
 
 func (esv5 *ESV5) OpenIndex(name string) *elastic.IndicesOpenService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.OpenIndex(name)
 }
 
 func (esv5 *ESV5) CloseIndex(name string) *elastic.IndicesCloseService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.CloseIndex(name)
 }
 
 func (esv5 *ESV5) IndexGet(indices ...string) *elastic.IndicesGetService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexGet(indices...)
 }
 
 func (esv5 *ESV5) IndexGetSettings(indices ...string) *elastic.IndicesGetSettingsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexGetSettings(indices...)
 }
 
 func (esv5 *ESV5) IndexPutSettings(indices ...string) *elastic.IndicesPutSettingsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexPutSettings(indices...)
 }
 
 func (esv5 *ESV5) IndexSegments(indices ...string) *elastic.IndicesSegmentsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexSegments(indices...)
 }
 
 func (esv5 *ESV5) IndexAnalyze() *elastic.IndicesAnalyzeService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexAnalyze()
 }
 
 func (esv5 *ESV5) Forcemerge(indices ...string) *elastic.IndicesForcemergeService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Forcemerge(indices...)
 }
 
 func (esv5 *ESV5) Refresh(indices ...string) *elastic.RefreshService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Refresh(indices...)
 }
 
 func (esv5 *ESV5) Flush(indices ...string) *elastic.IndicesFlushService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Flush(indices...)
 }
 
 func (esv5 *ESV5) Alias() *elastic.AliasService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Alias()
 }
 
 func (esv5 *ESV5) Aliases() *elastic.AliasesService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Aliases()
 }
 
 func (esv5 *ESV5) GetTemplate() *elastic.GetTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.GetTemplate()
 }
 
 func (esv5 *ESV5) PutTemplate() *elastic.PutTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.PutTemplate()
 }
 
 func (esv5 *ESV5) DeleteTemplate() *elastic.DeleteTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.DeleteTemplate()
 }
 
 func (esv5 *ESV5) IndexGetTemplate(names ...string) *elastic.IndicesGetTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexGetTemplate(names...)
 }
 
 func (esv5 *ESV5) IndexTemplateExists(name string) *elastic.IndicesExistsTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexTemplateExists(name)
 }
 
 func (esv5 *ESV5) IndexPutTemplate(name string) *elastic.IndicesPutTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexPutTemplate(name)
 }
 
 func (esv5 *ESV5) IndexDeleteTemplate(name string) *elastic.IndicesDeleteTemplateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexDeleteTemplate(name)
 }
 
 func (esv5 *ESV5) GetMapping() *elastic.IndicesGetMappingService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.GetMapping()
 }
 
 func (esv5 *ESV5) PutMapping() *elastic.IndicesPutMappingService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.PutMapping()
 }
 
 func (esv5 *ESV5) GetFieldMapping() *elastic.IndicesGetFieldMappingService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.GetFieldMapping()
 }
 
 func (esv5 *ESV5) IngestPutPipeline(id string) *elastic.IngestPutPipelineService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IngestPutPipeline(id)
 }
 
 func (esv5 *ESV5) IngestGetPipeline(ids ...string) *elastic.IngestGetPipelineService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IngestGetPipeline(ids...)
 }
 
 func (esv5 *ESV5) IngestDeletePipeline(id string) *elastic.IngestDeletePipelineService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IngestDeletePipeline(id)
 }
 
 func (esv5 *ESV5) IngestSimulatePipeline() *elastic.IngestSimulatePipelineService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IngestSimulatePipeline()
 }
 
 func (esv5 *ESV5) ClusterHealth() *elastic.ClusterHealthService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ClusterHealth()
 }
 
 func (esv5 *ESV5) ClusterState() *elastic.ClusterStateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ClusterState()
 }
 
 func (esv5 *ESV5) ClusterStats() *elastic.ClusterStatsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ClusterStats()
 }
 
 func (esv5 *ESV5) NodesInfo() *elastic.NodesInfoService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.NodesInfo()
 }
 
 func (esv5 *ESV5) NodesStats() *elastic.NodesStatsService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.NodesStats()
 }
 
 func (esv5 *ESV5) TasksCancel() *elastic.TasksCancelService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.TasksCancel()
 }
 
 func (esv5 *ESV5) TasksList() *elastic.TasksListService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.TasksList()
 }
 
 func (esv5 *ESV5) TasksGetTask() *elastic.TasksGetTaskService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.TasksGetTask()
 }
 
 func (esv5 *ESV5) SnapshotCreate(repository string, snapshot string) *elastic.SnapshotCreateService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SnapshotCreate(repository, snapshot)
 }
 
 func (esv5 *ESV5) SnapshotCreateRepository(repository string) *elastic.SnapshotCreateRepositoryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SnapshotCreateRepository(repository)
 }
 
 func (esv5 *ESV5) SnapshotDeleteRepository(repositories ...string) *elastic.SnapshotDeleteRepositoryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SnapshotDeleteRepository(repositories...)
 }
 
 func (esv5 *ESV5) SnapshotGetRepository(repositories ...string) *elastic.SnapshotGetRepositoryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SnapshotGetRepository(repositories...)
 }
 
 func (esv5 *ESV5) SnapshotVerifyRepository(repository string) *elastic.SnapshotVerifyRepositoryService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.SnapshotVerifyRepository(repository)
 }
 
 func (esv5 *ESV5) ElasticsearchVersion(url string) (string, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.ElasticsearchVersion(url)
 }
 
 func (esv5 *ESV5) IndexNames() ([]string, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.IndexNames()
 }
 
 func (esv5 *ESV5) Ping(url string) *elastic.PingService {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.Ping(url)
 }
 
 func (esv5 *ESV5) WaitForStatus(status string, timeout string) error {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.WaitForStatus(status, timeout)
 }
 
 func (esv5 *ESV5) WaitForGreenStatus(timeout string) error {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.WaitForGreenStatus(timeout)
 }
 
 func (esv5 *ESV5) WaitForYellowStatus(timeout string) error {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.ESV5), startTime)
 	return esv5.Client.WaitForYellowStatus(timeout)
 }
 
@@ -764,7 +595,7 @@ func InitESV5(opt ...Esv5OptionsFunc) (DpESV5, error) {
 
 	// http这里一些参数暂时写死，保证长连接
 	httpClient := &http.Client{
-		Transport: &http.Transport{
+		Transport: &esStatTransport{rt: &http.Transport{
 			Proxy: http.ProxyFromEnvironment,
 			DialContext: (&net.Dialer{
 				Timeout:   opts.connTimeout,
@@ -777,19 +608,58 @@ func InitESV5(opt ...Esv5OptionsFunc) (DpESV5, error) {
 
 			MaxIdleConns:        opts.maxIdleConnCount,
 			MaxIdleConnsPerHost: opts.maxIdleConnCount,
-		},
+		}},
 		Timeout: opts.timeout,
 	}
 
 	client, err := elastic.NewClient(
 		elastic.SetURL(opts.addr),
 		elastic.SetHttpClient(httpClient),
-		elastic.SetTraceLog(elastic.Logger(log.New(os.Stdout, "[es] ", log.Ldate|log.Ltime|log.Lshortfile))),
-		elastic.SetInfoLog(elastic.Logger(log.New(os.Stdout, "[es] ", log.Ldate|log.Ltime|log.Lshortfile))),
-		elastic.SetErrorLog(elastic.Logger(log.New(os.Stdout, "[es] ", log.Ldate|log.Ltime|log.Lshortfile))))
+		elastic.SetTraceLog(log.NewPrintLogger(log.LevelDebug, "[es] ")),
+		elastic.SetInfoLog(log.NewPrintLogger(log.LevelInfo, "[es] ")),
+		elastic.SetErrorLog(log.NewPrintLogger(log.LevelError, "[es] ")))
 
 	if err != nil {
 		return nil, err
 	}
 	return &ESV5{client}, nil
 }
+
+// es的各个Service只是拼装请求，统计放在http层，包含sniff和健康检查的请求，
+// 通过Do(ctx)传入的ctx中有trace时同时创建span
+type esStatTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *esStatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	startTime := time.Now()
+	op := esOp(req.Method, req.URL.Path)
+	_, span := startSpan(req.Context(), stat.ESV5, op, req.URL.Host)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	resp, err := t.rt.RoundTrip(req)
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
+		span.SetAttribute("http.status_code", strconv.Itoa(statusCode))
+	}
+	endSpan(span, err)
+	stat.ClientStatV2(stat.Labels{
+		Client: stat.ESV5,
+		Op:     op,
+		Addr:   req.URL.Host,
+		Code:   stat.HttpCode(statusCode, err),
+	}, startTime)
+	return resp, err
+}
+
+// /index/type/_search => _search，没有endpoint的文档操作用http method表示
+func esOp(method, path string) string {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(segments[i], "_") {
+			return segments[i]
+		}
+	}
+	return method
+}
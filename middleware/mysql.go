@@ -5,18 +5,21 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
-	"log"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 
-	"github.com/zer0131/toolbox"
+	"github.com/zer0131/toolbox/log"
 	"github.com/zer0131/toolbox/stat"
 )
 
 var (
 	errMysqlParam string = "%s illegal when init mysql"
+
+	// mysqlAddrs 记录InitMysql创建的*sql.DB对应的地址，用于统计和trace，
+	// 不放在Mysql中是为了不改变Mysql的字段
+	mysqlAddrs sync.Map
 )
 
 type Mysql struct {
@@ -131,125 +134,143 @@ type DpMysql interface {
 }
 
 func (mysql *Mysql) GetDB() *sql.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	return mysql.DB
 }
 
-func (mysql *Mysql) PingContext(ctx context.Context) error {
+func (mysql *Mysql) PingContext(ctx context.Context) (err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("PingContext", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "PingContext", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.PingContext(ctx)
 }
 
-func (mysql *Mysql) Ping() error {
+func (mysql *Mysql) Ping() (err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Ping", startTime, err) }()
 	return mysql.DB.Ping()
 }
 
 func (mysql *Mysql) Close() error {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	mysqlAddrs.Delete(mysql.DB)
 	return mysql.DB.Close()
 }
 
 func (mysql *Mysql) SetMaxIdleConns(n int) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	mysql.DB.SetMaxIdleConns(n)
 }
 
 func (mysql *Mysql) SetMaxOpenConns(n int) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	mysql.DB.SetMaxOpenConns(n)
 }
 
 func (mysql *Mysql) SetConnMaxLifetime(d time.Duration) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	mysql.DB.SetConnMaxLifetime(d)
 }
 
 func (mysql *Mysql) Stats() sql.DBStats {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	return mysql.DB.Stats()
 }
 
-func (mysql *Mysql) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+func (mysql *Mysql) PrepareContext(ctx context.Context, query string) (stmt *sql.Stmt, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("PrepareContext", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "PrepareContext", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.PrepareContext(ctx, query)
 }
 
-func (mysql *Mysql) Prepare(query string) (*sql.Stmt, error) {
+func (mysql *Mysql) Prepare(query string) (stmt *sql.Stmt, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Prepare", startTime, err) }()
 	return mysql.DB.Prepare(query)
 }
 
-func (mysql *Mysql) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+func (mysql *Mysql) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("ExecContext", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "ExecContext", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.ExecContext(ctx, query, args...)
 }
 
-func (mysql *Mysql) Exec(query string, args ...interface{}) (sql.Result, error) {
+func (mysql *Mysql) Exec(query string, args ...interface{}) (result sql.Result, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Exec", startTime, err) }()
 	return mysql.DB.Exec(query, args...)
 }
 
-func (mysql *Mysql) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+func (mysql *Mysql) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("QueryContext", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "QueryContext", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.QueryContext(ctx, query, args...)
 }
 
-func (mysql *Mysql) Query(query string, args ...interface{}) (*sql.Rows, error) {
+func (mysql *Mysql) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Query", startTime, err) }()
 	return mysql.DB.Query(query, args...)
 }
 
 func (mysql *Mysql) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
-	return mysql.DB.QueryRowContext(ctx, query, args...)
+	ctx, span := startSpan(ctx, stat.Mysql, "QueryRowContext", mysql.addr())
+	row := mysql.DB.QueryRowContext(ctx, query, args...)
+	mysql.stat("QueryRowContext", startTime, row.Err())
+	endSpan(span, row.Err())
+	return row
 }
 
 func (mysql *Mysql) QueryRow(query string, args ...interface{}) *sql.Row {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
-	return mysql.DB.QueryRow(query, args...)
+	row := mysql.DB.QueryRow(query, args...)
+	mysql.stat("QueryRow", startTime, row.Err())
+	return row
 }
 
-func (mysql *Mysql) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+func (mysql *Mysql) BeginTx(ctx context.Context, opts *sql.TxOptions) (tx *sql.Tx, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("BeginTx", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "BeginTx", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.BeginTx(ctx, opts)
 }
 
-func (mysql *Mysql) Begin() (*sql.Tx, error) {
+func (mysql *Mysql) Begin() (tx *sql.Tx, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Begin", startTime, err) }()
 	return mysql.DB.Begin()
 }
 
 func (mysql *Mysql) Driver() driver.Driver {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
 	return mysql.DB.Driver()
 }
 
-func (mysql *Mysql) Conn(ctx context.Context) (*sql.Conn, error) {
+func (mysql *Mysql) Conn(ctx context.Context) (conn *sql.Conn, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Mysql), startTime)
+	defer func() { mysql.stat("Conn", startTime, err) }()
+	ctx, span := startSpan(ctx, stat.Mysql, "Conn", mysql.addr())
+	defer func() { endSpan(span, err) }()
 	return mysql.DB.Conn(ctx)
 }
 
+func (mysql *Mysql) addr() string {
+	return mysqlAddr(mysql.DB)
+}
+
+// mysqlAddr 不是通过InitMysql创建的*sql.DB返回空，InitMysqlX复用同一个*sql.DB
+func mysqlAddr(db *sql.DB) string {
+	addr, _ := mysqlAddrs.Load(db)
+	s, _ := addr.(string)
+	return s
+}
+
+func (mysql *Mysql) stat(op string, startTime time.Time, err error) {
+	stat.ClientStatErr(stat.Mysql, op, mysql.addr(), startTime, err)
+}
+
 func assemblyConfigAndRegisterDial(opt ...MysqlOptionsFunc) (*mysql.Config, mysqlOptions, error) {
 	opts := defaultMysqlOptions
 	for _, o := range opt {
@@ -298,9 +319,10 @@ func InitMysql(opt ...MysqlOptionsFunc) (DpMysql, error) {
 		return nil, err
 	}
 
-	err = mysql.SetLogger(mysql.Logger(log.New(os.Stdout, "[mysql] ", log.Ldate|log.Ltime|log.Lshortfile)))
+	err = mysql.SetLogger(log.NewPrintLogger(log.LevelError, "[mysql] "))
 	if err != nil {
 		return nil, err
 	}
-	return &Mysql{db}, nil
+	mysqlAddrs.Store(db, opts.addr)
+	return &Mysql{DB: db}, nil
 }
@@ -3,15 +3,12 @@ package middleware
 import (
 	"context"
 	"database/sql"
-	"github.com/zer0131/toolbox"
-	"github.com/zer0131/toolbox/stat"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	//"github.com/jinzhu/gorm"
 	"gorm.io/gorm"
-	"time"
 )
 
 func InitMysqlORM(opt ...MysqlOptionsFunc) (DpMysqlORM, error) {
@@ -21,7 +18,18 @@ func InitMysqlORM(opt ...MysqlOptionsFunc) (DpMysqlORM, error) {
 	}
 
 	//db, err := gorm.Open("mysql", dbCfg.FormatDSN())
-	db, err := gorm.Open(mysql.Open(dbCfg.FormatDSN()), &gorm.Config{})
+	// 默认的logger输出到标准输出，换成toolbox/log，MysqlOrmLogMode开启时输出所有sql
+	logLevel := logger.Warn
+	if opts.ormLogMode {
+		logLevel = logger.Info
+	}
+	db, err := gorm.Open(mysql.Open(dbCfg.FormatDSN()), &gorm.Config{
+		Logger: NewLoggerMe(logger.Config{
+			SlowThreshold:             opts.slowThreshold,
+			LogLevel:                  logLevel,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +43,9 @@ func InitMysqlORM(opt ...MysqlOptionsFunc) (DpMysqlORM, error) {
 	if err := sqlDb.Ping(); err != nil {
 		return nil, err
 	}
+	if err := registerGormStat(db, opts.addr); err != nil {
+		return nil, err
+	}
 
 	return &MysqlORM{opts, db}, nil
 }
@@ -237,351 +248,235 @@ type DpMysqlORM interface {
 }
 
 func (mysqlorm *MysqlORM) Session(config *gorm.Session) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Session(config)
 }
 
 func (mysqlorm *MysqlORM) WithContext(ctx context.Context) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.WithContext(ctx)
 }
 
 func (mysqlorm *MysqlORM) Debug() *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Debug()
 }
 
 func (mysqlorm *MysqlORM) Set(key string, value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Set(key, value)
 }
 
 func (mysqlorm *MysqlORM) Get(key string) (interface{}, bool) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Get(key)
 }
 
 func (mysqlorm *MysqlORM) InstanceSet(key string, value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.InstanceSet(key, value)
 }
 
 func (mysqlorm *MysqlORM) InstanceGet(key string) (interface{}, bool) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.InstanceGet(key)
 }
 
 func (mysqlorm *MysqlORM) Create(value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Create(value)
 }
 
 func (mysqlorm *MysqlORM) CreateInBatches(value interface{}, batchSize int) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.CreateInBatches(value, batchSize)
 }
 
 func (mysqlorm *MysqlORM) Save(value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Save(value)
 }
 
 func (mysqlorm *MysqlORM) First(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.First(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) Take(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Take(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) Last(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Last(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) Find(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Find(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) FindInBatches(dest interface{}, batchSize int, fc func(tx *gorm.DB, batch int) error) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.FindInBatches(dest, batchSize, fc)
 }
 
 func (mysqlorm *MysqlORM) FirstOrInit(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.FirstOrInit(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) FirstOrCreate(dest interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.FirstOrCreate(dest, conds...)
 }
 
 func (mysqlorm *MysqlORM) Update(column string, value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Update(column, value)
 }
 
 func (mysqlorm *MysqlORM) Updates(values interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Updates(values)
 }
 
 func (mysqlorm *MysqlORM) UpdateColumn(column string, value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.UpdateColumn(column, value)
 }
 
 func (mysqlorm *MysqlORM) UpdateColumns(values interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.UpdateColumns(values)
 }
 
 func (mysqlorm *MysqlORM) Delete(value interface{}, conds ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Delete(value, conds...)
 }
 
 func (mysqlorm *MysqlORM) Count(count *int64) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Count(count)
 }
 
 func (mysqlorm *MysqlORM) Row() *sql.Row {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Row()
 }
 
 func (mysqlorm *MysqlORM) Rows() (*sql.Rows, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Rows()
 }
 
 func (mysqlorm *MysqlORM) Scan(dest interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Scan(dest)
 }
 
 func (mysqlorm *MysqlORM) Pluck(column string, dest interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Pluck(column, dest)
 }
 
 func (mysqlorm *MysqlORM) ScanRows(rows *sql.Rows, dest interface{}) error {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.ScanRows(rows, dest)
 }
 
 func (mysqlorm *MysqlORM) Transaction(fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) (err error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Transaction(fc, opts...)
 }
 
 func (mysqlorm *MysqlORM) Begin(opts ...*sql.TxOptions) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Begin(opts...)
 }
 
 func (mysqlorm *MysqlORM) Commit() *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Commit()
 }
 
 func (mysqlorm *MysqlORM) Rollback() *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Rollback()
 }
 
 func (mysqlorm *MysqlORM) SavePoint(name string) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.SavePoint(name)
 }
 
 func (mysqlorm *MysqlORM) RollbackTo(name string) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.RollbackTo(name)
 }
 
 func (mysqlorm *MysqlORM) Exec(sql string, values ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Exec(sql, values)
 }
 
 func (mysqlorm *MysqlORM) Model(value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Model(value)
 }
 
 func (mysqlorm *MysqlORM) Clauses(conds ...clause.Expression) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Clauses(conds...)
 }
 
 func (mysqlorm *MysqlORM) Table(name string, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Table(name, args...)
 }
 
 func (mysqlorm *MysqlORM) Distinct(args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Distinct(args...)
 }
 
 func (mysqlorm *MysqlORM) Select(query interface{}, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Select(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Omit(columns ...string) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Omit(columns...)
 }
 
 func (mysqlorm *MysqlORM) Where(query interface{}, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Where(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Not(query interface{}, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Not(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Or(query interface{}, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Or(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Joins(query string, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Joins(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Group(name string) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Group(name)
 }
 
 func (mysqlorm *MysqlORM) Having(query interface{}, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Having(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Order(value interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Order(value)
 }
 
 func (mysqlorm *MysqlORM) Limit(limit int) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Limit(limit)
 }
 
 func (mysqlorm *MysqlORM) Offset(offset int) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Offset(offset)
 }
 
 func (mysqlorm *MysqlORM) Scopes(funcs ...func(*gorm.DB) *gorm.DB) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Scopes(funcs...)
 }
 
 func (mysqlorm *MysqlORM) Preload(query string, args ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Preload(query, args...)
 }
 
 func (mysqlorm *MysqlORM) Attrs(attrs ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Attrs(attrs...)
 }
 
 func (mysqlorm *MysqlORM) Assign(attrs ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Assign(attrs...)
 }
 
 func (mysqlorm *MysqlORM) Unscoped() *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Unscoped()
 }
 
 func (mysqlorm *MysqlORM) Raw(sql string, values ...interface{}) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	return mysqlorm.DB.Raw(sql, values...)
 }
 
 func (mysqlorm *MysqlORM) GetDB() *sql.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	sqlDb, _ := mysqlorm.DB.DB()
 	return sqlDb
 }
 
 func (mysqlorm *MysqlORM) SetCtx(ctx context.Context) *gorm.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlORM), startTime)
 	newLogger := NewLoggerMe(logger.Config{
 		SlowThreshold:             mysqlorm.slowThreshold, // 慢 SQL 阈值
 		LogLevel:                  logger.Silent,          // 日志级别
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zer0131/toolbox/stat"
+	"github.com/zer0131/toolbox/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	gormStatStartKey = "toolbox:stat_start"
+	gormSpanKey      = "toolbox:span"
+)
+
+// gorm的Where、Limit等方法只是拼装语句，统计放在callback中，
+// 只有真正执行sql时才会上报
+func registerGormStat(db *gorm.DB, addr string) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("*").Register("toolbox:stat_before_create", gormStatBefore("create", addr)),
+		cb.Create().After("*").Register("toolbox:stat_after_create", gormStatAfter("create", addr)),
+		cb.Query().Before("*").Register("toolbox:stat_before_query", gormStatBefore("query", addr)),
+		cb.Query().After("*").Register("toolbox:stat_after_query", gormStatAfter("query", addr)),
+		cb.Update().Before("*").Register("toolbox:stat_before_update", gormStatBefore("update", addr)),
+		cb.Update().After("*").Register("toolbox:stat_after_update", gormStatAfter("update", addr)),
+		cb.Delete().Before("*").Register("toolbox:stat_before_delete", gormStatBefore("delete", addr)),
+		cb.Delete().After("*").Register("toolbox:stat_after_delete", gormStatAfter("delete", addr)),
+		cb.Row().Before("*").Register("toolbox:stat_before_row", gormStatBefore("row", addr)),
+		cb.Row().After("*").Register("toolbox:stat_after_row", gormStatAfter("row", addr)),
+		cb.Raw().Before("*").Register("toolbox:stat_before_raw", gormStatBefore("raw", addr)),
+		cb.Raw().After("*").Register("toolbox:stat_after_raw", gormStatAfter("raw", addr)),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func gormStatBefore(op, addr string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(gormStatStartKey, time.Now())
+		// WithContext传入的ctx中有trace时创建span，下游的driver调用也使用这个ctx
+		if ctx, span := startSpan(db.Statement.Context, stat.MysqlORM, op, addr); span != nil {
+			db.Statement.Context = ctx
+			db.InstanceSet(gormSpanKey, span)
+		}
+	}
+}
+
+func gormStatAfter(op, addr string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		defer gormEndSpan(db)
+
+		v, ok := db.InstanceGet(gormStatStartKey)
+		if !ok {
+			return
+		}
+		startTime, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		err := db.Error
+		// 记录不存在不算失败
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		stat.ClientStatErr(stat.MysqlORM, op, addr, startTime, err)
+	}
+}
+
+func gormEndSpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(*trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.statement", db.Statement.SQL.String())
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	endSpan(span, err)
+}
@@ -3,7 +3,6 @@ package middleware
 import (
 	"database/sql"
 	"github.com/jmoiron/sqlx"
-	"github.com/zer0131/toolbox"
 	"github.com/zer0131/toolbox/stat"
 	"time"
 )
@@ -36,97 +35,102 @@ type DpMysqlX interface {
 }
 
 func (mysqlx *MysqlX) DriverName() string {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
 	return mysqlx.DB.DriverName()
 }
 
 func (mysqlx *MysqlX) MapperFunc(mf func(string) string) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
 	mysqlx.DB.MapperFunc(mf)
 }
 
 func (mysqlx *MysqlX) Rebind(query string) string {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
 	return mysqlx.DB.Rebind(query)
 }
 
 func (mysqlx *MysqlX) Unsafe() *sqlx.DB {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
 	return mysqlx.DB.Unsafe()
 }
 
 func (mysqlx *MysqlX) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
-	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
 	return mysqlx.DB.BindNamed(query, arg)
 }
 
-func (mysqlx *MysqlX) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
+func (mysqlx *MysqlX) NamedQuery(query string, arg interface{}) (rows *sqlx.Rows, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("NamedQuery", startTime, err) }()
 	return mysqlx.DB.NamedQuery(query, arg)
 }
 
-func (mysqlx *MysqlX) NamedExec(query string, arg interface{}) (sql.Result, error) {
+func (mysqlx *MysqlX) NamedExec(query string, arg interface{}) (result sql.Result, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("NamedExec", startTime, err) }()
 	return mysqlx.DB.NamedExec(query, arg)
 }
 
-func (mysqlx *MysqlX) Select(dest interface{}, query string, args ...interface{}) error {
+func (mysqlx *MysqlX) Select(dest interface{}, query string, args ...interface{}) (err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("Select", startTime, err) }()
 	return mysqlx.DB.Select(dest, query, args...)
 }
 
-func (mysqlx *MysqlX) Get(dest interface{}, query string, args ...interface{}) error {
+func (mysqlx *MysqlX) Get(dest interface{}, query string, args ...interface{}) (err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("Get", startTime, noRowsStatErr(err)) }()
 	return mysqlx.DB.Get(dest, query, args...)
 }
 
 func (mysqlx *MysqlX) MustBegin() *sqlx.Tx {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
-	return mysqlx.DB.MustBegin()
+	tx := mysqlx.DB.MustBegin()
+	mysqlx.stat("MustBegin", startTime, nil)
+	return tx
 }
 
-func (mysqlx *MysqlX) Beginx() (*sqlx.Tx, error) {
+func (mysqlx *MysqlX) Beginx() (tx *sqlx.Tx, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("Beginx", startTime, err) }()
 	return mysqlx.DB.Beginx()
 }
 
-func (mysqlx *MysqlX) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+func (mysqlx *MysqlX) Queryx(query string, args ...interface{}) (rows *sqlx.Rows, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("Queryx", startTime, err) }()
 	return mysqlx.DB.Queryx(query, args...)
 }
 
 func (mysqlx *MysqlX) QueryRowx(query string, args ...interface{}) *sqlx.Row {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
-	return mysqlx.DB.QueryRowx(query, args...)
+	row := mysqlx.DB.QueryRowx(query, args...)
+	mysqlx.stat("QueryRowx", startTime, row.Err())
+	return row
 }
 
 func (mysqlx *MysqlX) MustExec(query string, args ...interface{}) sql.Result {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
-	return mysqlx.DB.MustExec(query, args...)
+	result := mysqlx.DB.MustExec(query, args...)
+	mysqlx.stat("MustExec", startTime, nil)
+	return result
 }
 
-func (mysqlx *MysqlX) Preparex(query string) (*sqlx.Stmt, error) {
+func (mysqlx *MysqlX) Preparex(query string) (stmt *sqlx.Stmt, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("Preparex", startTime, err) }()
 	return mysqlx.DB.Preparex(query)
 }
 
-func (mysqlx *MysqlX) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+func (mysqlx *MysqlX) PrepareNamed(query string) (stmt *sqlx.NamedStmt, err error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.MysqlX), startTime)
+	defer func() { mysqlx.stat("PrepareNamed", startTime, err) }()
 	return mysqlx.DB.PrepareNamed(query)
 }
+
+func (mysqlx *MysqlX) stat(op string, startTime time.Time, err error) {
+	stat.ClientStatErr(stat.MysqlX, op, mysqlAddr(mysqlx.DB.DB), startTime, err)
+}
+
+// 查不到数据不算失败
+func noRowsStatErr(err error) error {
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
@@ -1,9 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"github.com/gomodule/redigo/redis"
-	"github.com/zer0131/toolbox"
 	"github.com/zer0131/toolbox/stat"
+	"github.com/zer0131/toolbox/trace"
 	"time"
 )
 
@@ -11,12 +12,22 @@ import (
 // 因为会造成用户code中也import上面的github路径，这样就
 // 不能控制app的使用方式。
 type RedigoPool struct {
-	rp *redis.Pool
+	rp   *redis.Pool
+	addr string
 }
 type DpRedigoPool interface {
 	Get() redis.Conn
 }
 
+// DpRedigoContextPool InitRedigo返回的pool同时实现了该接口，需要时通过类型断言使用，
+// 单独定义是为了不破坏DpRedigoPool已有的实现
+type DpRedigoContextPool interface {
+	DpRedigoPool
+
+	// 获取连接时受ctx约束，返回的连接执行命令时以ctx剩余的时间作为读超时
+	GetContext(ctx context.Context) (redis.Conn, error)
+}
+
 func InitRedigo(opt ...RedigoOptionsFunc) (DpRedigoPool, error) {
 	opts := defaultRedigoOptions
 	for _, o := range opt {
@@ -45,11 +56,128 @@ func InitRedigo(opt ...RedigoOptionsFunc) (DpRedigoPool, error) {
 			)
 		},
 	}
-	return &RedigoPool{rp: &pool}, nil
+	return &RedigoPool{rp: &pool, addr: opts.addr}, nil
 }
 
 func (redigo *RedigoPool) Get() redis.Conn {
+	return &redigoConn{Conn: redigo.rp.Get(), addr: redigo.addr}
+}
+
+func (redigo *RedigoPool) GetContext(ctx context.Context) (redis.Conn, error) {
+	conn, err := redigo.rp.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &redigoConn{Conn: conn, addr: redigo.addr, ctx: ctx}, nil
+}
+
+// redigoConn 在Do中统计，拿到命令名和执行结果，
+// 同时实现redis.ConnWithTimeout，redis.DoWithTimeout、redis.ReceiveWithTimeout可以继续使用。
+// Send的命令在Receive或Do读到结果时统计，耗时从Send开始计算，不创建span；
+// 直到Close都没有读取结果的命令不统计
+type redigoConn struct {
+	redis.Conn
+	addr    string
+	ctx     context.Context
+	pending []pendingCmd
+}
+
+type pendingCmd struct {
+	name      string
+	startTime time.Time
+}
+
+func (c *redigoConn) Send(commandName string, args ...interface{}) error {
+	startTime := time.Now()
+	err := c.Conn.Send(commandName, args...)
+	if err == nil {
+		c.pending = append(c.pending, pendingCmd{name: commandName, startTime: startTime})
+	}
+	return err
+}
+
+func (c *redigoConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	c.receivePending(err)
+	return reply, err
+}
+
+func (c *redigoConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.process(commandName, func() (interface{}, error) {
+		return c.do(commandName, args...)
+	})
+}
+
+func (c *redigoConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	return c.process(commandName, func() (interface{}, error) {
+		return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
+	})
+}
+
+func (c *redigoConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	reply, err := redis.ReceiveWithTimeout(c.Conn, timeout)
+	c.receivePending(err)
+	return reply, err
+}
+
+// receivePending 统计最早Send的命令
+func (c *redigoConn) receivePending(err error) {
+	if len(c.pending) == 0 {
+		return
+	}
+	cmd := c.pending[0]
+	c.pending = c.pending[1:]
+	stat.ClientStatErr(stat.Redigo, cmd.name, c.addr, cmd.startTime, err)
+}
+
+// donePending Do会读取之前Send的全部结果。Do("")返回每个命令的结果，
+// 其它命令只返回最后一个结果，这时redis返回的错误无法对应到具体命令，只统计连接错误
+func (c *redigoConn) donePending(commandName string, reply interface{}, err error) {
+	if _, ok := err.(redis.Error); ok {
+		err = nil
+	}
+	replies, _ := reply.([]interface{})
+	for i, cmd := range c.pending {
+		cmdErr := err
+		if commandName == "" && i < len(replies) {
+			if e, ok := replies[i].(redis.Error); ok {
+				cmdErr = e
+			}
+		}
+		stat.ClientStatErr(stat.Redigo, cmd.name, c.addr, cmd.startTime, cmdErr)
+	}
+	c.pending = nil
+}
+
+// process 统计并为命令创建span
+func (c *redigoConn) process(commandName string, fn func() (interface{}, error)) (interface{}, error) {
 	startTime := time.Now()
-	defer stat.ClientStat(toolbox.StatMetrix(stat.Redigo), startTime)
-	return redigo.rp.Get()
+	var span *trace.Span
+	if c.ctx != nil && commandName != "" {
+		_, span = startSpan(c.ctx, stat.Redigo, commandName, c.addr)
+	}
+	reply, err := fn()
+	c.donePending(commandName, reply, err)
+	// Do("")只是flush之前Send的命令
+	if commandName != "" {
+		stat.ClientStatErr(stat.Redigo, commandName, c.addr, startTime, err)
+		endSpan(span, err)
+	}
+	return reply, err
+}
+
+// do 通过GetContext获取的连接，ctx有deadline时只等待剩余的时间
+func (c *redigoConn) do(commandName string, args ...interface{}) (interface{}, error) {
+	if c.ctx == nil {
+		return c.Conn.Do(commandName, args...)
+	}
+	deadline, ok := c.ctx.Deadline()
+	if !ok {
+		return c.Conn.Do(commandName, args...)
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return redis.DoWithTimeout(c.Conn, timeout, commandName, args...)
 }
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/zer0131/toolbox/stat"
+)
+
+type statRecorder struct {
+	mutex  sync.Mutex
+	labels []stat.Labels
+}
+
+func (r *statRecorder) Report(labels stat.Labels, cost time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.labels = append(r.labels, labels)
+}
+
+// fakeRedigoConn 按顺序返回replies，pending记录Send之后还没有读取的个数
+type fakeRedigoConn struct {
+	replies []interface{}
+	pending int
+}
+
+func (c *fakeRedigoConn) Close() error { return nil }
+func (c *fakeRedigoConn) Err() error   { return nil }
+func (c *fakeRedigoConn) Flush() error { return nil }
+
+func (c *fakeRedigoConn) Send(commandName string, args ...interface{}) error {
+	c.pending++
+	return nil
+}
+
+func (c *fakeRedigoConn) next() interface{} {
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply
+}
+
+func (c *fakeRedigoConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.DoWithTimeout(0, commandName, args...)
+}
+
+func (c *fakeRedigoConn) DoWithTimeout(timeout time.Duration, commandName string, args ...interface{}) (interface{}, error) {
+	replies := make([]interface{}, 0, c.pending)
+	for ; c.pending > 0; c.pending-- {
+		replies = append(replies, c.next())
+	}
+	if commandName == "" {
+		return replies, nil
+	}
+	return c.next(), nil
+}
+
+func (c *fakeRedigoConn) Receive() (interface{}, error) {
+	return c.ReceiveWithTimeout(0)
+}
+
+func (c *fakeRedigoConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	c.pending--
+	return c.next(), nil
+}
+
+func newFakeRedigoPool(conn *fakeRedigoConn) *RedigoPool {
+	return &RedigoPool{
+		rp:   &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }},
+		addr: "127.0.0.1:6379",
+	}
+}
+
+func TestRedigoDoWithTimeout(t *testing.T) {
+	rec := &statRecorder{}
+	stat.RegisterReporter(rec)
+	defer stat.ResetReporters()
+
+	pool := newFakeRedigoPool(&fakeRedigoConn{replies: []interface{}{"v", "PONG"}})
+	conn := pool.Get()
+	defer conn.Close()
+
+	reply, err := redis.String(redis.DoWithTimeout(conn, time.Second, "GET", "k"))
+	if err != nil || reply != "v" {
+		t.Fatalf("unexpected reply %v err %v", reply, err)
+	}
+	_ = conn.Send("PING")
+	_ = conn.Flush()
+	if reply, err := redis.String(redis.ReceiveWithTimeout(conn, time.Second)); err != nil || reply != "PONG" {
+		t.Fatalf("unexpected reply %v err %v", reply, err)
+	}
+
+	expect := stat.Labels{Client: stat.Redigo, Op: "GET", Addr: "127.0.0.1:6379", Code: stat.ErrCode(nil)}
+	if len(rec.labels) != 2 || rec.labels[0] != expect || rec.labels[1].Op != "PING" {
+		t.Errorf("expect %+v actual %+v", expect, rec.labels)
+	}
+}
+
+func TestRedigoPipelineStat(t *testing.T) {
+	rec := &statRecorder{}
+	stat.RegisterReporter(rec)
+	defer stat.ResetReporters()
+
+	fake := &fakeRedigoConn{replies: []interface{}{"OK", "v", redis.Error("ERR"), int64(1), int64(0)}}
+	pool := newFakeRedigoPool(fake)
+	ctxPool, ok := DpRedigoPool(pool).(DpRedigoContextPool)
+	if !ok {
+		t.Fatal("RedigoPool should implement DpRedigoContextPool")
+	}
+	conn := ctxPool.Get()
+	defer conn.Close()
+
+	_ = conn.Send("SET", "k", "v")
+	_ = conn.Send("GET", "k")
+	if _, err := conn.Receive(); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Send("INCR", "k")
+	if _, err := conn.Do(""); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Send("DEL", "k")
+	if _, err := conn.Do("DEL", "k"); err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []string
+	for _, l := range rec.labels {
+		ops = append(ops, l.Op+":"+l.Code)
+	}
+	expect := []string{
+		"SET:" + stat.ErrCode(nil),
+		"GET:" + stat.ErrCode(nil),
+		"INCR:" + stat.ErrCode(redis.Error("ERR")),
+		"DEL:" + stat.ErrCode(nil),
+		"DEL:" + stat.ErrCode(nil),
+	}
+	if strings.Join(ops, ",") != strings.Join(expect, ",") {
+		t.Errorf("expect %v actual %v", expect, ops)
+	}
+}
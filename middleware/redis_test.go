@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zer0131/toolbox/stat"
+	"github.com/zer0131/toolbox/trace"
+)
+
+func TestRedisWithContext(t *testing.T) {
+	rec := &statRecorder{}
+	stat.RegisterReporter(rec)
+	defer stat.ResetReporters()
+	exporter := trace.NewInMemoryExporter()
+	trace.RegisterExporter(exporter)
+	defer trace.ResetExporters()
+
+	client, err := InitRedis(RedisMethod(TYPE_PROXY), RedisAddr("127.0.0.1:1"), RedisMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.(*RedisClient).Close()
+	ctxClient, ok := client.(DpRedisContextClient)
+	if !ok {
+		t.Fatal("RedisClient should implement DpRedisContextClient")
+	}
+
+	ctx, parent := trace.Start(context.Background(), "parent", trace.KindInternal)
+	defer parent.End()
+	if err := ctxClient.WithContext(ctx).Ping().Err(); err == nil {
+		t.Fatal("expect err for closed port")
+	}
+
+	if len(rec.labels) != 1 || rec.labels[0].Op != "ping" || rec.labels[0].Addr != "127.0.0.1:1" {
+		t.Errorf("unexpected stat %+v", rec.labels)
+	}
+	spans := exporter.Spans()
+	if len(spans) != 1 || spans[0].Attributes["net.peer.name"] != "127.0.0.1:1" {
+		t.Errorf("unexpected spans %+v", spans)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/zer0131/toolbox/trace"
+)
+
+// startSpan ctx中已经有trace时创建client span，没有时返回nil span（方法都可以安全调用），
+// 避免定时任务等没有上游请求的调用产生大量只有一个span的trace
+func startSpan(ctx context.Context, system, op, addr string) (context.Context, *trace.Span) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+	newCtx, span := trace.Start(ctx, system+" "+op, trace.KindClient)
+	span.SetAttribute("db.system", system)
+	span.SetAttribute("db.operation", op)
+	span.SetAttribute("net.peer.name", addr)
+	return newCtx, span
+}
+
+func endSpan(span *trace.Span, err error) {
+	span.SetError(err)
+	span.End()
+}
@@ -0,0 +1,250 @@
+package prometheusmetrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zer0131/toolbox/httplib"
+	"github.com/zer0131/toolbox/stat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics 直接使用prometheus的CounterVec/HistogramVec打点，
+// 不经过go-metrics转换，rate和histogram_quantile结果是准确的。
+// 注册到stat之后，middleware和httplib中的下游调用都会上报到这里：
+//
+//	m, err := prometheusmetrics.NewMetrics(prometheusmetrics.MetricsWithNamespace("app"))
+//	stat.RegisterReporter(m)
+type Metrics struct {
+	gatherer prometheus.Gatherer
+	pathFunc PathFunc
+
+	clientRequests *prometheus.CounterVec
+	clientLatency  *prometheus.HistogramVec
+	httpRequests   *prometheus.CounterVec
+	httpLatency    *prometheus.HistogramVec
+	grpcRequests   *prometheus.CounterVec
+	grpcLatency    *prometheus.HistogramVec
+	grpcRejected   *prometheus.CounterVec
+}
+
+type metricsOptions struct {
+	namespace  string
+	subsystem  string
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	buckets    []float64
+	pathFunc   PathFunc
+}
+
+// UnmatchedPath HttpMw中PathFunc返回空、HttpMwForGin中没有匹配到路由时path的label
+const UnmatchedPath = "unmatched"
+
+// PathFunc 把请求转为有限的path label，例如路由模板，返回空时记为UnmatchedPath
+type PathFunc func(r *http.Request) string
+
+// KnownPaths 只保留这些url path，其它的记为UnmatchedPath
+func KnownPaths(paths ...string) PathFunc {
+	known := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		known[p] = struct{}{}
+	}
+	return func(r *http.Request) string {
+		if _, ok := known[r.URL.Path]; ok {
+			return r.URL.Path
+		}
+		return ""
+	}
+}
+
+var defaultMetricsOptions = metricsOptions{
+	registerer: prometheus.DefaultRegisterer,
+	gatherer:   prometheus.DefaultGatherer,
+	buckets:    prometheus.DefBuckets,
+}
+
+type MetricsOptionsFunc func(*metricsOptions)
+
+func MetricsWithNamespace(s string) MetricsOptionsFunc {
+	return func(o *metricsOptions) {
+		o.namespace = s
+	}
+}
+
+func MetricsWithSubsystem(s string) MetricsOptionsFunc {
+	return func(o *metricsOptions) {
+		o.subsystem = s
+	}
+}
+
+// MetricsWithRegistry 使用独立的registry，不传则使用prometheus默认的registry
+func MetricsWithRegistry(r *prometheus.Registry) MetricsOptionsFunc {
+	return func(o *metricsOptions) {
+		o.registerer = r
+		o.gatherer = r
+	}
+}
+
+// MetricsWithBuckets 耗时分桶，单位秒
+func MetricsWithBuckets(b []float64) MetricsOptionsFunc {
+	return func(o *metricsOptions) {
+		o.buckets = b
+	}
+}
+
+// MetricsWithPathFunc HttpMw中path label的取值，url path中带参数时不能直接使用，
+// 否则label数量不可控，默认所有请求都记为UnmatchedPath
+func MetricsWithPathFunc(fn PathFunc) MetricsOptionsFunc {
+	return func(o *metricsOptions) {
+		o.pathFunc = fn
+	}
+}
+
+func NewMetrics(opt ...MetricsOptionsFunc) (*Metrics, error) {
+	opts := defaultMetricsOptions
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	m := &Metrics{
+		gatherer: opts.gatherer,
+		pathFunc: opts.pathFunc,
+		clientRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "client_requests_total",
+			Help:      "Total number of requests to downstream dependencies.",
+		}, []string{"client", "op", "addr", "code"}),
+		clientLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "client_request_duration_seconds",
+			Help:      "Latency of requests to downstream dependencies.",
+			Buckets:   opts.buckets,
+		}, []string{"client", "op", "addr"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "http_server_requests_total",
+			Help:      "Total number of http requests handled.",
+		}, []string{"method", "path", "code"}),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "http_server_request_duration_seconds",
+			Help:      "Latency of http requests handled.",
+			Buckets:   opts.buckets,
+		}, []string{"method", "path"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "grpc_server_handled_total",
+			Help:      "Total number of grpc calls handled.",
+		}, []string{"method", "code"}),
+		grpcLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "grpc_server_handling_seconds",
+			Help:      "Latency of grpc calls handled.",
+			Buckets:   opts.buckets,
+		}, []string{"method"}),
+		grpcRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.namespace,
+			Subsystem: opts.subsystem,
+			Name:      "grpc_server_ratelimit_rejected_total",
+			Help:      "Total number of grpc calls rejected by rate limit.",
+		}, []string{"method", "code"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.clientRequests, m.clientLatency,
+		m.httpRequests, m.httpLatency,
+		m.grpcRequests, m.grpcLatency, m.grpcRejected,
+	} {
+		if err := opts.registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Report 实现stat.Reporter
+func (m *Metrics) Report(labels stat.Labels, cost time.Duration) {
+	m.clientRequests.WithLabelValues(labels.Client, labels.Op, labels.Addr, labels.Code).Inc()
+	m.clientLatency.WithLabelValues(labels.Client, labels.Op, labels.Addr).Observe(cost.Seconds())
+}
+
+// Handler 暴露/metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// HandlerForGin 暴露/metrics，例如 r.GET("/metrics", m.HandlerForGin())
+func (m *Metrics) HandlerForGin() gin.HandlerFunc {
+	return gin.WrapH(m.Handler())
+}
+
+// HttpMw http server打点，path由MetricsWithPathFunc决定
+func (m *Metrics) HttpMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		sw := httplib.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+		var path string
+		if m.pathFunc != nil {
+			path = m.pathFunc(r)
+		}
+		m.observeHttp(r.Method, path, sw.Status(), startTime)
+	})
+}
+
+// HttpMwForGin gin打点，path使用路由模板，没有匹配到路由的请求记为UnmatchedPath
+func (m *Metrics) HttpMwForGin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		c.Next()
+		m.observeHttp(c.Request.Method, c.FullPath(), c.Writer.Status(), startTime)
+	}
+}
+
+func (m *Metrics) observeHttp(method, path string, statusCode int, startTime time.Time) {
+	if path == "" {
+		path = UnmatchedPath
+	}
+	m.httpRequests.WithLabelValues(method, path, strconv.Itoa(statusCode)).Inc()
+	m.httpLatency.WithLabelValues(method, path).Observe(time.Since(startTime).Seconds())
+}
+
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGrpc(info.FullMethod, err, startTime)
+		return resp, err
+	}
+}
+
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+		err := handler(srv, stream)
+		m.observeGrpc(info.FullMethod, err, startTime)
+		return err
+	}
+}
+
+// ObserveRateLimit 实现ratelimit.RejectedFunc，调用方不作为label，避免label数量不可控
+func (m *Metrics) ObserveRateLimit(ctx context.Context, method, caller, code string) {
+	m.grpcRejected.WithLabelValues(method, code).Inc()
+}
+
+func (m *Metrics) observeGrpc(method string, err error, startTime time.Time) {
+	m.grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.grpcLatency.WithLabelValues(method).Observe(time.Since(startTime).Seconds())
+}
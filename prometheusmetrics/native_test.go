@@ -0,0 +1,114 @@
+package prometheusmetrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zer0131/toolbox/stat"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsReport(t *testing.T) {
+	m, err := NewMetrics(MetricsWithNamespace("test"), MetricsWithRegistry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new metrics err: %s", err)
+	}
+	stat.RegisterReporter(m)
+	defer stat.ResetReporters()
+
+	start := time.Now()
+	stat.ClientStatErr(stat.Redis, "get", "127.0.0.1:6379", start, nil)
+	stat.ClientStatErr(stat.Redis, "get", "127.0.0.1:6379", start, nil)
+	stat.ClientStatErr(stat.Redis, "get", "127.0.0.1:6379", start, errors.New("connection refused"))
+	stat.ClientStatErr(stat.Redis, "get", "127.0.0.1:6379", start, context.DeadlineExceeded)
+
+	if v := testutil.ToFloat64(m.clientRequests.WithLabelValues(stat.Redis, "get", "127.0.0.1:6379", stat.CodeOK)); v != 2 {
+		t.Errorf("expect 2 ok actual %v", v)
+	}
+	if v := testutil.ToFloat64(m.clientRequests.WithLabelValues(stat.Redis, "get", "127.0.0.1:6379", stat.CodeError)); v != 1 {
+		t.Errorf("expect 1 error actual %v", v)
+	}
+	if v := testutil.ToFloat64(m.clientRequests.WithLabelValues(stat.Redis, "get", "127.0.0.1:6379", stat.CodeTimeout)); v != 1 {
+		t.Errorf("expect 1 timeout actual %v", v)
+	}
+}
+
+func TestMetricsRegisterConflict(t *testing.T) {
+	r := prometheus.NewRegistry()
+	if _, err := NewMetrics(MetricsWithRegistry(r)); err != nil {
+		t.Fatalf("new metrics err: %s", err)
+	}
+	if _, err := NewMetrics(MetricsWithRegistry(r)); err == nil {
+		t.Fatalf("expect register conflict err")
+	}
+}
+
+func TestMetricsHttpMw(t *testing.T) {
+	m, err := NewMetrics(MetricsWithRegistry(prometheus.NewRegistry()), MetricsWithPathFunc(KnownPaths("/foo")))
+	if err != nil {
+		t.Fatalf("new metrics err: %s", err)
+	}
+
+	h := m.HttpMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/foo", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/2", nil))
+
+	if v := testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/foo", "404")); v != 1 {
+		t.Errorf("expect 1 actual %v", v)
+	}
+	// 不认识的path归到同一个label
+	if v := testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, UnmatchedPath, "404")); v != 2 {
+		t.Errorf("expect 2 unmatched actual %v", v)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	if !strings.Contains(string(body), `http_server_requests_total{code="404",method="GET",path="/foo"} 1`) {
+		t.Errorf("metrics handler output unexpected:\n%s", body)
+	}
+}
+
+func TestMetricsUnaryServerInterceptor(t *testing.T) {
+	m, err := NewMetrics(MetricsWithRegistry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new metrics err: %s", err)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/helloworld.Greeter/SayHello"}
+	_, _ = m.UnaryServerInterceptor()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+
+	if v := testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, codes.NotFound.String())); v != 1 {
+		t.Errorf("expect 1 actual %v", v)
+	}
+}
+
+func TestMetricsObserveRateLimit(t *testing.T) {
+	m, err := NewMetrics(MetricsWithRegistry(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("new metrics err: %s", err)
+	}
+
+	m.ObserveRateLimit(context.Background(), "/helloworld.Greeter/SayHello", "10.0.0.1", "method_limited")
+	if v := testutil.ToFloat64(m.grpcRejected.WithLabelValues("/helloworld.Greeter/SayHello", "method_limited")); v != 1 {
+		t.Errorf("expect 1 actual %v", v)
+	}
+	if n := testutil.CollectAndCount(m.clientRequests); n != 0 {
+		t.Errorf("expect no client requests actual %d", n)
+	}
+}
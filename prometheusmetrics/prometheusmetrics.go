@@ -1,6 +1,7 @@
 package prometheusmetrics
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,48 +9,63 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox/log"
 )
 
-// PrometheusConfig prometheus暴露配置
+// PrometheusConfig prometheus暴露配置，定时把go-metrics的Registry转换为prometheus指标：
+// Counter/Gauge转为gauge，Meter转为counter，Histogram/Timer转为summary
 type PrometheusConfig struct {
-	namespace        string
-	Registry         metrics.Registry // Registry to be exported
-	subsystem        string
-	promRegistry     prometheus.Registerer //Prometheus registry
-	FlushInterval    time.Duration         //interval to update prom prometheusmetrics
-	gauges           map[string]prometheus.Gauge
-	customMetrics    map[string]*CustomCollector
-	histogramBuckets []float64
-	timerBuckets     []float64
-	mutex            *sync.Mutex
+	namespace          string
+	Registry           metrics.Registry // Registry to be exported
+	subsystem          string
+	promRegistry       prometheus.Registerer //Prometheus registry
+	FlushInterval      time.Duration         //interval to update prom prometheusmetrics
+	gauges             map[string]prometheus.Gauge
+	customMetrics      map[string]*CustomCollector
+	histogramQuantiles []float64
+	timerQuantiles     []float64
+	push               *pushConfig
+	mutex              *sync.Mutex
 }
 
 // NewPrometheusProvider 新建一个prometheus配置
 func NewPrometheusProvider(r metrics.Registry, namespace string, subsystem string, promRegistry prometheus.Registerer, FlushInterval time.Duration) *PrometheusConfig {
 	return &PrometheusConfig{
-		namespace:        namespace,
-		subsystem:        subsystem,
-		Registry:         r,
-		promRegistry:     promRegistry,
-		FlushInterval:    FlushInterval,
-		gauges:           make(map[string]prometheus.Gauge),
-		customMetrics:    make(map[string]*CustomCollector),
-		histogramBuckets: []float64{0.05, 0.1, 0.25, 0.50, 0.75, 0.9, 0.95, 0.99},
-		timerBuckets:     []float64{0.50, 0.95, 0.99, 0.999},
-		mutex:            new(sync.Mutex),
+		namespace:          namespace,
+		subsystem:          subsystem,
+		Registry:           r,
+		promRegistry:       promRegistry,
+		FlushInterval:      FlushInterval,
+		gauges:             make(map[string]prometheus.Gauge),
+		customMetrics:      make(map[string]*CustomCollector),
+		histogramQuantiles: []float64{0.05, 0.1, 0.25, 0.50, 0.75, 0.9, 0.95, 0.99},
+		timerQuantiles:     []float64{0.50, 0.95, 0.99, 0.999},
+		mutex:              new(sync.Mutex),
 	}
 }
 
-func (c *PrometheusConfig) WithHistogramBuckets(b []float64) *PrometheusConfig {
-	c.histogramBuckets = b
+// WithHistogramQuantiles histogram导出为summary时的分位数
+func (c *PrometheusConfig) WithHistogramQuantiles(q []float64) *PrometheusConfig {
+	c.histogramQuantiles = q
 	return c
 }
 
-func (c *PrometheusConfig) WithTimerBuckets(b []float64) *PrometheusConfig {
-	c.timerBuckets = b
+// WithTimerQuantiles timer导出为summary时的分位数
+func (c *PrometheusConfig) WithTimerQuantiles(q []float64) *PrometheusConfig {
+	c.timerQuantiles = q
 	return c
 }
 
+// WithHistogramBuckets 历史命名，实际设置的是分位数，请使用WithHistogramQuantiles
+func (c *PrometheusConfig) WithHistogramBuckets(b []float64) *PrometheusConfig {
+	return c.WithHistogramQuantiles(b)
+}
+
+// WithTimerBuckets 历史命名，实际设置的是分位数，请使用WithTimerQuantiles
+func (c *PrometheusConfig) WithTimerBuckets(b []float64) *PrometheusConfig {
+	return c.WithTimerQuantiles(b)
+}
+
 func (c *PrometheusConfig) flattenKey(key string) string {
 	key = strings.Replace(key, " ", "_", -1)
 	key = strings.Replace(key, ".", "_", -1)
@@ -63,7 +79,7 @@ func (c *PrometheusConfig) createKey(name string) string {
 	return fmt.Sprintf("%s_%s_%s", c.namespace, c.subsystem, name)
 }
 
-func (c *PrometheusConfig) gaugeFromNameAndValue(name string, val float64) {
+func (c *PrometheusConfig) gaugeFromNameAndValue(name string, val float64) error {
 	key := c.createKey(name)
 	g, ok := c.gauges[key]
 	if !ok {
@@ -73,129 +89,207 @@ func (c *PrometheusConfig) gaugeFromNameAndValue(name string, val float64) {
 			Name:      c.flattenKey(name),
 			Help:      name,
 		})
-		_ = c.promRegistry.Register(g)
+		if err := c.promRegistry.Register(g); err != nil {
+			return err
+		}
 		c.gauges[key] = g
 	}
 	g.Set(val)
+	return nil
 }
 
-func (c *PrometheusConfig) histogramFromNameAndMetric(name string, goMetric interface{}, buckets []float64) {
-	key := c.createKey(name)
-
+// 同一个名字第一次出现时注册collector，之后只替换其中的const metric
+func (c *PrometheusConfig) customCollector(name, suffix string) (*CustomCollector, error) {
+	key := c.createKey(name + suffix)
 	collector, ok := c.customMetrics[key]
-	if !ok {
-		collector = NewCustomCollector(c.mutex)
-		c.promRegistry.MustRegister(collector)
-		c.customMetrics[key] = collector
+	if ok {
+		return collector, nil
 	}
 
-	var ps []float64
-	var count uint64
-	var sum float64
-	var typeName string
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(
+			c.flattenKey(c.namespace),
+			c.flattenKey(c.subsystem),
+			c.flattenKey(name)+suffix,
+		),
+		name,
+		nil,
+		nil,
+	)
+	collector = NewCustomCollectorWithDesc(desc)
+	if err := c.promRegistry.Register(collector); err != nil {
+		return nil, err
+	}
+	c.customMetrics[key] = collector
+	return collector, nil
+}
+
+func (c *PrometheusConfig) counterFromNameAndValue(name string, val float64) error {
+	collector, err := c.customCollector(name, "_total")
+	if err != nil {
+		return err
+	}
+	metric, err := prometheus.NewConstMetric(collector.desc, prometheus.CounterValue, val)
+	if err != nil {
+		return err
+	}
+	collector.set(metric)
+	return nil
+}
+
+// go-metrics的histogram是采样后的分位数，对应prometheus的summary而不是histogram。
+// 采样池只保留部分数据，sum用采样的均值乘以总次数近似，count是准确的
+func (c *PrometheusConfig) summaryFromNameAndMetric(name string, goMetric interface{}) error {
+	var (
+		suffix    string
+		quantiles []float64
+		ps        []float64
+		count     uint64
+		sum       float64
+	)
 
 	switch metric := goMetric.(type) {
 	case metrics.Histogram:
 		snapshot := metric.Snapshot()
-		ps = snapshot.Percentiles(buckets)
+		quantiles = c.histogramQuantiles
+		ps = snapshot.Percentiles(quantiles)
 		count = uint64(snapshot.Count())
-		sum = float64(snapshot.Sum())
-		typeName = "histogram"
+		sum = snapshot.Mean() * float64(count)
 	case metrics.Timer:
+		// timer内部单位是纳秒，按prometheus的习惯转为秒
 		snapshot := metric.Snapshot()
-		ps = snapshot.Percentiles(buckets)
+		suffix = "_seconds"
+		quantiles = c.timerQuantiles
+		ps = snapshot.Percentiles(quantiles)
+		for i := range ps {
+			ps[i] = ps[i] / float64(time.Second)
+		}
 		count = uint64(snapshot.Count())
-		sum = float64(snapshot.Sum())
-		typeName = "timer"
+		sum = snapshot.Mean() * float64(count) / float64(time.Second)
 	default:
-		panic(fmt.Sprintf("unexpected metric type %T", goMetric))
+		return fmt.Errorf("unexpected metric type %T", goMetric)
 	}
 
-	bucketVals := make(map[float64]uint64)
-	for ii, bucket := range buckets {
-		bucketVals[bucket] = uint64(ps[ii])
+	collector, err := c.customCollector(name, suffix)
+	if err != nil {
+		return err
 	}
 
-	desc := prometheus.NewDesc(
-		prometheus.BuildFQName(
-			c.flattenKey(c.namespace),
-			c.flattenKey(c.subsystem),
-			fmt.Sprintf("%s_%s", c.flattenKey(name), typeName),
-		),
-		c.flattenKey(name),
-		[]string{},
-		map[string]string{},
-	)
+	quantileVals := make(map[float64]float64, len(quantiles))
+	for i, q := range quantiles {
+		quantileVals[q] = ps[i]
+	}
 
-	if constHistogram, err := prometheus.NewConstHistogram(
-		desc,
-		count,
-		sum,
-		bucketVals,
-	); err == nil {
-		c.mutex.Lock()
-		collector.metric = constHistogram
-		c.mutex.Unlock()
+	metric, err := prometheus.NewConstSummary(collector.desc, count, sum, quantileVals)
+	if err != nil {
+		return err
 	}
+	collector.set(metric)
+	return nil
 }
 
+// UpdatePrometheusMetrics 按FlushInterval定时刷新，不会退出，需要停止时使用UpdatePrometheusMetricsContext
 func (c *PrometheusConfig) UpdatePrometheusMetrics() {
-	for _ = range time.Tick(c.FlushInterval) {
-		_ = c.UpdatePrometheusMetricsOnce()
+	_ = c.UpdatePrometheusMetricsContext(context.Background())
+}
+
+// UpdatePrometheusMetricsContext 按FlushInterval定时刷新，ctx结束后返回ctx.Err()。
+// 开启push模式时每次刷新后推送，退出前再刷新并推送一次，避免丢失最后一个周期的数据
+func (c *PrometheusConfig) UpdatePrometheusMetricsContext(ctx context.Context) error {
+	ticker := time.NewTicker(c.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if c.push != nil {
+				// ctx已经结束，最后一次推送不受其控制，重试次数由WithPushRetry限制
+				c.flushAndPush(context.Background())
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			c.flushAndPush(ctx)
+		}
+	}
+}
+
+func (c *PrometheusConfig) flushAndPush(ctx context.Context) {
+	if err := c.UpdatePrometheusMetricsOnce(); err != nil {
+		log.Errorf(ctx, "update prometheus metrics err=%s", err)
+	}
+	if err := c.Push(ctx); err != nil {
+		log.Errorf(ctx, "push prometheus metrics err=%s", err)
 	}
 }
 
+// UpdatePrometheusMetricsOnce 刷新一次，单个指标失败不影响其他指标，返回第一个错误
 func (c *PrometheusConfig) UpdatePrometheusMetricsOnce() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var firstErr error
 	c.Registry.Each(func(name string, i interface{}) {
+		var err error
 		switch metric := i.(type) {
 		case metrics.Counter:
-			c.gaugeFromNameAndValue(name, float64(metric.Count()))
+			err = c.gaugeFromNameAndValue(name, float64(metric.Count()))
 		case metrics.Gauge:
-			c.gaugeFromNameAndValue(name, float64(metric.Value()))
+			err = c.gaugeFromNameAndValue(name, float64(metric.Value()))
 		case metrics.GaugeFloat64:
-			c.gaugeFromNameAndValue(name, float64(metric.Value()))
+			err = c.gaugeFromNameAndValue(name, metric.Value())
 		case metrics.Histogram:
-			samples := metric.Snapshot().Sample().Values()
-			if len(samples) > 0 {
-				lastSample := samples[len(samples)-1]
-				c.gaugeFromNameAndValue(name, float64(lastSample))
-			}
-
-			c.histogramFromNameAndMetric(name, metric, c.histogramBuckets)
+			err = c.summaryFromNameAndMetric(name, metric)
 		case metrics.Meter:
-			lastSample := metric.Snapshot().Rate1()
-			c.gaugeFromNameAndValue(name, float64(lastSample))
+			err = c.counterFromNameAndValue(name, float64(metric.Count()))
 		case metrics.Timer:
-			lastSample := metric.Snapshot().Rate1()
-			c.gaugeFromNameAndValue(name, float64(lastSample))
-
-			c.histogramFromNameAndMetric(name, metric, c.timerBuckets)
+			err = c.summaryFromNameAndMetric(name, metric)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("metric %s: %w", name, err)
 		}
 	})
-	return nil
+	return firstErr
 }
 
+// CustomCollector 保存一个go-metrics指标最近一次转换的结果
 type CustomCollector struct {
-	prometheus.Collector
-
+	desc   *prometheus.Desc
 	metric prometheus.Metric
 	mutex  *sync.Mutex
 }
 
+// NewCustomCollector 没有desc，注册为unchecked collector，请使用NewCustomCollectorWithDesc
 func NewCustomCollector(mutex *sync.Mutex) *CustomCollector {
 	return &CustomCollector{
 		mutex: mutex,
 	}
 }
 
+// NewCustomCollectorWithDesc Describe时返回desc，注册时prometheus会校验指标名冲突
+func NewCustomCollectorWithDesc(desc *prometheus.Desc) *CustomCollector {
+	return &CustomCollector{
+		desc:  desc,
+		mutex: new(sync.Mutex),
+	}
+}
+
+func (c *CustomCollector) set(metric prometheus.Metric) {
+	c.mutex.Lock()
+	c.metric = metric
+	c.mutex.Unlock()
+}
+
 func (c *CustomCollector) Collect(ch chan<- prometheus.Metric) {
 	c.mutex.Lock()
-	if c.metric != nil {
-		val := c.metric
-		ch <- val
-	}
+	metric := c.metric
 	c.mutex.Unlock()
+	if metric != nil {
+		ch <- metric
+	}
 }
 
-func (p *CustomCollector) Describe(ch chan<- *prometheus.Desc) {
+func (c *CustomCollector) Describe(ch chan<- *prometheus.Desc) {
+	if c.desc != nil {
+		ch <- c.desc
+	}
 }
@@ -1,9 +1,11 @@
 package prometheusmetrics
 
 import (
+	"context"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rcrowley/go-metrics"
+	"sync"
 	"testing"
 	"time"
 )
@@ -109,7 +111,7 @@ func TestPrometheusMeterGetUpdated(t *testing.T) {
 		t.Fatalf("prometheus was unable to register the metric")
 	}
 	serialized := fmt.Sprint(metricss[0])
-	expected := fmt.Sprintf("name:\"test_subsys_meter\" help:\"meter\" type:GAUGE metric:<gauge:<value:%.16f > > ", gm.Rate1())
+	expected := fmt.Sprintf("name:\"test_subsys_meter_total\" help:\"meter\" type:COUNTER metric:<counter:<value:%d > > ", gm.Count())
 	if serialized != expected {
 		t.Fatalf("Go-metrics value and prometheus metrics value do not match")
 	}
@@ -119,8 +121,7 @@ func TestPrometheusHistogramGetUpdated(t *testing.T) {
 	prometheusRegistry := prometheus.NewRegistry()
 	metricsRegistry := metrics.NewRegistry()
 	pClient := NewPrometheusProvider(metricsRegistry, "test", "subsys", prometheusRegistry, 1*time.Second)
-	// values := make([]int64, 0)
-	//sample := metrics.HistogramSnapshot{metrics.NewSampleSnapshot(int64(len(values)), values)}
+	pClient.WithHistogramQuantiles([]float64{0.5, 0.95})
 	gm := metrics.NewHistogram(metrics.NewUniformSample(1028))
 	_ = metricsRegistry.Register("metric", gm)
 
@@ -132,18 +133,104 @@ func TestPrometheusHistogramGetUpdated(t *testing.T) {
 	}
 	gm.Update(10)
 
-	go pClient.UpdatePrometheusMetrics()
-	time.Sleep(5 * time.Second)
+	if err := pClient.UpdatePrometheusMetricsOnce(); err != nil {
+		t.Fatalf("update err: %s", err)
+	}
 	metricss, _ := prometheusRegistry.Gather()
 
-	if len(metricss) < 2 {
+	if len(metricss) != 1 {
 		t.Fatalf("prometheus was unable to register the metric")
 	}
 
-	serialized := fmt.Sprint(metricss[1])
+	serialized := fmt.Sprint(metricss[0])
 
-	expected := `name:"test_subsys_metric_histogram" help:"metric" type:HISTOGRAM metric:<histogram:<sample_count:100 sample_sum:129 bucket:<cumulative_count:1 upper_bound:0.05 > bucket:<cumulative_count:1 upper_bound:0.1 > bucket:<cumulative_count:1 upper_bound:0.25 > bucket:<cumulative_count:1 upper_bound:0.5 > bucket:<cumulative_count:1 upper_bound:0.75 > bucket:<cumulative_count:1 upper_bound:0.9 > bucket:<cumulative_count:5 upper_bound:0.95 > bucket:<cumulative_count:9 upper_bound:0.99 > > > `
+	expected := `name:"test_subsys_metric" help:"metric" type:SUMMARY metric:<summary:<sample_count:100 sample_sum:129 quantile:<quantile:0.5 value:1 > quantile:<quantile:0.95 value:5 > > > `
 	if serialized != expected {
 		t.Fatalf("Go-metrics value and prometheus metrics value for max do not match:\n+ %s\n- %s", serialized, expected)
 	}
 }
+
+func TestPrometheusHistogramSumApproximation(t *testing.T) {
+	prometheusRegistry := prometheus.NewRegistry()
+	metricsRegistry := metrics.NewRegistry()
+	pClient := NewPrometheusProvider(metricsRegistry, "test", "subsys", prometheusRegistry, 1*time.Second)
+	gm := metrics.NewHistogram(metrics.NewUniformSample(10))
+	_ = metricsRegistry.Register("metric", gm)
+
+	// 采样池只有10个，sum按均值乘以总次数估算，而不是采样数据之和
+	for ii := 0; ii < 100; ii++ {
+		gm.Update(2)
+	}
+
+	if err := pClient.UpdatePrometheusMetricsOnce(); err != nil {
+		t.Fatalf("update err: %s", err)
+	}
+	metricss, _ := prometheusRegistry.Gather()
+	summary := metricss[0].GetMetric()[0].GetSummary()
+	if summary.GetSampleCount() != 100 || summary.GetSampleSum() != 200 {
+		t.Fatalf("expect count 100 sum 200 actual count %d sum %v", summary.GetSampleCount(), summary.GetSampleSum())
+	}
+}
+
+func TestNewCustomCollector(t *testing.T) {
+	collector := NewCustomCollector(new(sync.Mutex))
+	collector.set(prometheus.MustNewConstMetric(prometheus.NewDesc("custom", "custom", nil, nil), prometheus.GaugeValue, 1))
+
+	prometheusRegistry := prometheus.NewRegistry()
+	if err := prometheusRegistry.Register(collector); err != nil {
+		t.Fatalf("register err: %s", err)
+	}
+	metricss, err := prometheusRegistry.Gather()
+	if err != nil || len(metricss) != 1 || metricss[0].GetName() != "custom" {
+		t.Fatalf("unexpected gather result %v err %v", metricss, err)
+	}
+}
+
+func TestPrometheusTimerGetUpdated(t *testing.T) {
+	prometheusRegistry := prometheus.NewRegistry()
+	metricsRegistry := metrics.NewRegistry()
+	pClient := NewPrometheusProvider(metricsRegistry, "test", "subsys", prometheusRegistry, 1*time.Second)
+	pClient.WithTimerQuantiles([]float64{0.5})
+	tm := metrics.NewTimer()
+	_ = metricsRegistry.Register("timer", tm)
+	tm.Update(2 * time.Second)
+	tm.Update(2 * time.Second)
+
+	if err := pClient.UpdatePrometheusMetricsOnce(); err != nil {
+		t.Fatalf("update err: %s", err)
+	}
+	metricss, _ := prometheusRegistry.Gather()
+	if len(metricss) != 1 {
+		t.Fatalf("prometheus was unable to register the metric")
+	}
+	serialized := fmt.Sprint(metricss[0])
+	expected := `name:"test_subsys_timer_seconds" help:"timer" type:SUMMARY metric:<summary:<sample_count:2 sample_sum:4 quantile:<quantile:0.5 value:2 > > > `
+	if serialized != expected {
+		t.Fatalf("Go-metrics value and prometheus metrics value do not match:\n+ %s\n- %s", serialized, expected)
+	}
+}
+
+func TestUpdatePrometheusMetricsOnceConflict(t *testing.T) {
+	prometheusRegistry := prometheus.NewRegistry()
+	metricsRegistry := metrics.NewRegistry()
+	pClient := NewPrometheusProvider(metricsRegistry, "test", "subsys", prometheusRegistry, 1*time.Second)
+	_ = prometheusRegistry.Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "test",
+		Subsystem: "subsys",
+		Name:      "counter",
+		Help:      "counter",
+	}))
+	_ = metricsRegistry.Register("counter", metrics.NewCounter())
+	if err := pClient.UpdatePrometheusMetricsOnce(); err == nil {
+		t.Fatalf("expect register conflict err")
+	}
+}
+
+func TestUpdatePrometheusMetricsContext(t *testing.T) {
+	pClient := NewPrometheusProvider(metrics.NewRegistry(), "test", "subsys", prometheus.NewRegistry(), 10*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pClient.UpdatePrometheusMetricsContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect %s actual %v", context.DeadlineExceeded, err)
+	}
+}
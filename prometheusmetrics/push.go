@@ -0,0 +1,101 @@
+package prometheusmetrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/zer0131/toolbox"
+)
+
+const (
+	defaultPushRetry   = 3
+	defaultPushBackoff = 500 * time.Millisecond
+	defaultPushTimeout = 5 * time.Second
+)
+
+// pushConfig push模式配置，url为空时不推送
+type pushConfig struct {
+	url      string
+	job      string
+	grouping map[string]string
+	retry    int
+	backoff  time.Duration
+	timeout  time.Duration
+	gatherer prometheus.Gatherer
+}
+
+// WithPushGateway 开启push模式，每次刷新后以及UpdatePrometheusMetricsContext退出前
+// 把registry推送到Pushgateway，分组标签为job和instance(本机ip)
+func (c *PrometheusConfig) WithPushGateway(url, job string) *PrometheusConfig {
+	c.push = &pushConfig{
+		url:      url,
+		job:      job,
+		grouping: map[string]string{"instance": toolbox.LocalIP()},
+		retry:    defaultPushRetry,
+		backoff:  defaultPushBackoff,
+		timeout:  defaultPushTimeout,
+	}
+	// promRegistry一般是*prometheus.Registry，本身就是Gatherer
+	if g, ok := c.promRegistry.(prometheus.Gatherer); ok {
+		c.push.gatherer = g
+	} else {
+		c.push.gatherer = prometheus.DefaultGatherer
+	}
+	return c
+}
+
+// WithPushGrouping 追加分组标签，需要在WithPushGateway之后调用
+func (c *PrometheusConfig) WithPushGrouping(name, value string) *PrometheusConfig {
+	if c.push != nil {
+		c.push.grouping[name] = value
+	}
+	return c
+}
+
+// WithPushRetry 推送失败的重试次数和初始退避时间，每次重试退避时间翻倍
+func (c *PrometheusConfig) WithPushRetry(retry int, backoff time.Duration) *PrometheusConfig {
+	if c.push != nil {
+		c.push.retry = retry
+		c.push.backoff = backoff
+	}
+	return c
+}
+
+// WithPushTimeout 单次推送的http超时
+func (c *PrometheusConfig) WithPushTimeout(timeout time.Duration) *PrometheusConfig {
+	if c.push != nil {
+		c.push.timeout = timeout
+	}
+	return c
+}
+
+// Push 推送一次当前registry，失败时按退避重试，ctx结束时停止重试；未开启push模式时为空操作
+func (c *PrometheusConfig) Push(ctx context.Context) error {
+	if c.push == nil {
+		return nil
+	}
+
+	pusher := push.New(c.push.url, c.push.job).
+		Gatherer(c.push.gatherer).
+		Client(&http.Client{Timeout: c.push.timeout})
+	for name, value := range c.push.grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+
+	backoff := c.push.backoff
+	var err error
+	for i := 0; ; i++ {
+		if err = pusher.Push(); err == nil || i >= c.push.retry {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
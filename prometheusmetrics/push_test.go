@@ -0,0 +1,103 @@
+package prometheusmetrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rcrowley/go-metrics"
+	"github.com/zer0131/toolbox"
+)
+
+type pushRecorder struct {
+	mu       sync.Mutex
+	fail     int
+	paths    []string
+	lastBody string
+}
+
+func (p *pushRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail > 0 {
+		p.fail--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	p.paths = append(p.paths, r.Method+" "+r.URL.Path)
+	p.lastBody = string(body)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *pushRecorder) pushed() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.paths...)
+}
+
+func TestPushRetry(t *testing.T) {
+	rec := &pushRecorder{fail: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	r := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("counter", r).Inc(3)
+	pClient := NewPrometheusProvider(r, "test", "subsys", prometheus.NewRegistry(), time.Second).
+		WithPushGateway(srv.URL, "myjob").
+		WithPushRetry(2, time.Millisecond)
+	if err := pClient.UpdatePrometheusMetricsOnce(); err != nil {
+		t.Fatalf("update err: %s", err)
+	}
+	if err := pClient.Push(context.Background()); err != nil {
+		t.Fatalf("push err: %s", err)
+	}
+
+	paths := rec.pushed()
+	expect := "PUT /metrics/job/myjob/instance/" + toolbox.LocalIP()
+	if len(paths) != 1 || paths[0] != expect {
+		t.Fatalf("expect [%s] actual %v", expect, paths)
+	}
+	if !strings.Contains(rec.lastBody, "test_subsys_counter") {
+		t.Errorf("pushed body does not contain counter")
+	}
+}
+
+func TestPushRetryExhausted(t *testing.T) {
+	rec := &pushRecorder{fail: 10}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	pClient := NewPrometheusProvider(metrics.NewRegistry(), "test", "subsys", prometheus.NewRegistry(), time.Second).
+		WithPushGateway(srv.URL, "myjob").
+		WithPushRetry(1, time.Millisecond)
+	if err := pClient.Push(context.Background()); err == nil {
+		t.Fatalf("expect push err")
+	}
+	if rec.fail != 8 {
+		t.Errorf("expect 2 attempts actual %d", 10-rec.fail)
+	}
+}
+
+func TestPushOnShutdown(t *testing.T) {
+	rec := &pushRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	pClient := NewPrometheusProvider(metrics.NewRegistry(), "test", "subsys", prometheus.NewRegistry(), time.Hour).
+		WithPushGateway(srv.URL, "myjob")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pClient.UpdatePrometheusMetricsContext(ctx); err != context.Canceled {
+		t.Fatalf("expect %s actual %v", context.Canceled, err)
+	}
+	if paths := rec.pushed(); len(paths) != 1 {
+		t.Fatalf("expect 1 push on shutdown actual %v", paths)
+	}
+}
@@ -1,7 +1,12 @@
 package stat
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/url"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -13,11 +18,97 @@ const (
 	MysqlORM = "mysqlorm"
 	ESV5     = "esv5"
 	Http     = "http"
+	Grpc     = "grpc"
 )
 
+// 结果码，http类调用直接使用状态码，例如200、502，grpc调用使用状态码名称，例如OK、Unavailable
+const (
+	CodeOK      = "ok"
+	CodeError   = "error"
+	CodeTimeout = "timeout"
+)
+
+// Labels 描述一次下游调用，用于按依赖统计耗时与错误率
+type Labels struct {
+	Client string // 客户端类型，例如redis、mysql、http
+	Op     string // 操作，例如redis命令名、sql方法名、http path
+	Addr   string // 目标地址
+	Code   string // 结果码
+}
+
+// Reporter 统计上报的实现方，例如prometheus
+type Reporter interface {
+	Report(labels Labels, cost time.Duration)
+}
+
+var (
+	reporterMu sync.RWMutex
+	reporters  []Reporter
+)
+
+// RegisterReporter 注册上报实现，未注册时统计为空操作
+func RegisterReporter(r Reporter) {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	reporters = append(reporters, r)
+}
+
+// ResetReporters 清空已注册的上报实现
+func ResetReporters() {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	reporters = nil
+}
+
+// ClientStat 只有名字维度，请使用ClientStatV2
 func ClientStat(name string, start time.Time) {
-	//pfc.Meter(name, 1)
-	//pfc.Histogram(name, time.Since(start).Nanoseconds()/(1000*1000))
+	ClientStatV2(Labels{Client: name, Code: CodeOK}, start)
+}
+
+// ClientStatV2 上报一次下游调用
+func ClientStatV2(labels Labels, start time.Time) {
+	cost := time.Since(start)
+	reporterMu.RLock()
+	defer reporterMu.RUnlock()
+	for _, r := range reporters {
+		r.Report(labels, cost)
+	}
+}
+
+// ClientStatErr 根据err得到结果码后上报
+func ClientStatErr(client, op, addr string, start time.Time, err error) {
+	ClientStatV2(Labels{Client: client, Op: op, Addr: addr, Code: ErrCode(err)}, start)
+}
+
+// ErrCode 将err转换为结果码，超时单独区分
+func ErrCode(err error) string {
+	if err == nil {
+		return CodeOK
+	}
+	if IsTimeout(err) {
+		return CodeTimeout
+	}
+	return CodeError
+}
+
+// IsTimeout ctx超时或者网络超时
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// HttpCode 将http状态码转换为结果码，请求没有拿到响应时为error
+func HttpCode(statusCode int, err error) string {
+	if statusCode == 0 {
+		return ErrCode(err)
+	}
+	return strconv.Itoa(statusCode)
 }
 
 func GetRawPath(rawurl string) string {
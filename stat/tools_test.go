@@ -1,11 +1,74 @@
 package stat
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_GetRawPath(t *testing.T) {
 
 	fmt.Println(GetRawPath("/foo/bar?a=b"))
 }
+
+type testReporter struct {
+	labels []Labels
+}
+
+func (r *testReporter) Report(labels Labels, cost time.Duration) {
+	r.labels = append(r.labels, labels)
+}
+
+func Test_ClientStatV2(t *testing.T) {
+	r := &testReporter{}
+	RegisterReporter(r)
+	defer ResetReporters()
+
+	start := time.Now()
+	ClientStatErr(Redis, "get", "127.0.0.1:6379", start, nil)
+	ClientStatErr(Mysql, "Exec", "127.0.0.1:3306", start, errors.New("bad conn"))
+	ClientStatV2(Labels{Client: Http, Op: "/foo", Addr: "127.0.0.1:80", Code: HttpCode(502, nil)}, start)
+
+	expect := []Labels{
+		{Client: Redis, Op: "get", Addr: "127.0.0.1:6379", Code: CodeOK},
+		{Client: Mysql, Op: "Exec", Addr: "127.0.0.1:3306", Code: CodeError},
+		{Client: Http, Op: "/foo", Addr: "127.0.0.1:80", Code: "502"},
+	}
+	if !reflect.DeepEqual(r.labels, expect) {
+		t.Errorf("expect %+v actual %+v", expect, r.labels)
+	}
+}
+
+func Test_HttpCode(t *testing.T) {
+	if c := HttpCode(0, errors.New("timeout")); c != CodeError {
+		t.Errorf("expect %s actual %s", CodeError, c)
+	}
+	if c := HttpCode(200, nil); c != "200" {
+		t.Errorf("expect 200 actual %s", c)
+	}
+	if c := HttpCode(0, fmt.Errorf("get: %w", context.DeadlineExceeded)); c != CodeTimeout {
+		t.Errorf("expect %s actual %s", CodeTimeout, c)
+	}
+}
+
+func Test_IsTimeout(t *testing.T) {
+	var tests = []struct {
+		err    error
+		expect bool
+	}{
+		{err: nil, expect: false},
+		{err: errors.New("timeout"), expect: false},
+		{err: context.DeadlineExceeded, expect: true},
+		{err: &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, expect: true},
+	}
+	for i, tt := range tests {
+		if actual := IsTimeout(tt.err); actual != tt.expect {
+			t.Errorf("Index %d expect %v actual %v", i, tt.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// SpanData 结束后的span，交给Exporter
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          error
+}
+
+// Exporter span的导出实现，Export在End的goroutine中同步调用，耗时操作需要自己异步处理
+type Exporter interface {
+	Export(span SpanData)
+}
+
+var (
+	exporterMutex sync.RWMutex
+	exporters     []Exporter
+)
+
+// RegisterExporter 注册导出实现，未注册时span只用于传递trace id
+func RegisterExporter(e Exporter) {
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+	exporters = append(exporters, e)
+}
+
+// ResetExporters 清空已注册的导出实现
+func ResetExporters() {
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+	exporters = nil
+}
+
+func export(data SpanData) {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	for _, e := range exporters {
+		e.Export(data)
+	}
+}
+
+// InMemoryExporter 保存在内存中，用于测试
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(span SpanData) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans 按结束顺序返回
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = nil
+}
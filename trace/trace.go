@@ -0,0 +1,254 @@
+// 轻量的分布式追踪，span模型与OpenTelemetry一致，通过W3C traceparent在进程之间传递，
+// 可以通过Exporter把span转给OpenTelemetry、jaeger等系统
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentKey W3C Trace Context的header，http和grpc metadata都使用这个名字
+const TraceParentKey = "traceparent"
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext 需要在进程之间传递的部分
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceParent 编码为traceparent，例如00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) TraceParent() string {
+	var flags byte
+	if sc.Sampled {
+		flags = 1
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceParent 解析traceparent，格式不合法时第二个返回值为false，
+// traceparent来自上游请求，长度在解码之前检查
+func ParseTraceParent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	s = strings.TrimSpace(s)
+	// 规范要求小写
+	if strings.ToLower(s) != s {
+		return sc, false
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	// 00版本只有4段，之后的版本可能追加字段
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if _, err := hex.DecodeString(parts[0]); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+type SpanKind int
+
+const (
+	KindInternal SpanKind = iota
+	KindServer
+	KindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Span 一次操作，结束时调用End，之后的修改都会被忽略
+type Span struct {
+	mutex        sync.Mutex
+	name         string
+	kind         SpanKind
+	spanContext  SpanContext
+	parentSpanID SpanID
+	startTime    time.Time
+	attributes   map[string]string
+	err          error
+	ended        bool
+}
+
+// SpanContext nil span返回空的SpanContext
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+// SetAttribute 记录属性，例如http.url、db.statement
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.ended {
+		s.attributes[key] = value
+	}
+}
+
+// SetError 标记失败，err为nil时忽略
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.ended {
+		s.err = err
+	}
+}
+
+// End 结束并交给Exporter，重复调用只生效一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		Name:         s.name,
+		Kind:         s.kind,
+		SpanContext:  s.spanContext,
+		ParentSpanID: s.parentSpanID,
+		StartTime:    s.startTime,
+		EndTime:      time.Now(),
+		Attributes:   s.attributes,
+		Err:          s.err,
+	}
+	s.mutex.Unlock()
+
+	if s.spanContext.Sampled {
+		export(data)
+	}
+}
+
+type spanCtxKey struct{}
+type remoteSpanCtxKey struct{}
+
+// SpanFromContext 当前的span，没有时返回nil，nil span的方法都可以安全调用
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext 当前span的SpanContext，没有本地span时使用从上游解析出的SpanContext
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.spanContext
+	}
+	sc, _ := ctx.Value(remoteSpanCtxKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext 保存从上游解析出的SpanContext，之后Start的span作为它的子span
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanCtxKey{}, sc)
+}
+
+// Start 创建span，ctx中有span或上游的SpanContext时作为子span，否则开始新的trace
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		name:       name,
+		kind:       kind,
+		startTime:  time.Now(),
+		attributes: make(map[string]string),
+	}
+	if parent.IsValid() {
+		s.spanContext.TraceID = parent.TraceID
+		s.spanContext.Sampled = parent.Sampled
+		s.parentSpanID = parent.SpanID
+	} else {
+		s.spanContext.TraceID = newTraceID()
+		s.spanContext.Sampled = true
+	}
+	s.spanContext.SpanID = newSpanID()
+	return context.WithValue(ctx, spanCtxKey{}, s), s
+}
+
+var (
+	randMutex  sync.Mutex
+	randSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func newTraceID() TraceID {
+	var t TraceID
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	for !t.IsValid() {
+		randSource.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	for !s.IsValid() {
+		randSource.Read(s[:])
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	var tests = []struct {
+		s       string
+		ok      bool
+		sampled bool
+	}{
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true, sampled: true},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", ok: true},
+		{s: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{s: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{s: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01"},
+		{s: "bad"},
+		// 超长的字段不能panic
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e47364bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b700f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0101"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{s: "zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01"},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g"},
+		{s: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", ok: true, sampled: true},
+		{s: "00-" + strings.Repeat("a", 1<<16) + "-00f067aa0ba902b7-01"},
+	}
+	for i, tt := range tests {
+		sc, ok := ParseTraceParent(tt.s)
+		if ok != tt.ok || sc.Sampled != tt.sampled {
+			t.Errorf("Index %d expect %v %v actual %v %v", i, tt.ok, tt.sampled, ok, sc.Sampled)
+		}
+		if ok && !strings.HasPrefix(tt.s, "01-") && sc.TraceParent() != tt.s {
+			t.Errorf("Index %d expect %s actual %s", i, tt.s, sc.TraceParent())
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	RegisterExporter(exporter)
+	defer ResetExporters()
+
+	remote, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+
+	ctx, server := Start(ctx, "server", KindServer)
+	_, client := Start(ctx, "client", KindClient)
+	client.SetAttribute("k", "v")
+	client.SetError(errors.New("failed"))
+	client.End()
+	client.End()
+	server.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans actual %d", len(spans))
+	}
+	if spans[0].Name != "client" || spans[0].Attributes["k"] != "v" || spans[0].Err == nil {
+		t.Errorf("unexpected client span %+v", spans[0])
+	}
+	if spans[0].ParentSpanID != spans[1].SpanContext.SpanID || spans[1].ParentSpanID != remote.SpanID {
+		t.Errorf("unexpected parent %s %s", spans[0].ParentSpanID, spans[1].ParentSpanID)
+	}
+	for _, s := range spans {
+		if s.SpanContext.TraceID != remote.TraceID {
+			t.Errorf("expect trace id %s actual %s", remote.TraceID, s.SpanContext.TraceID)
+		}
+	}
+}
+
+func TestNotSampled(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	RegisterExporter(exporter)
+	defer ResetExporters()
+
+	remote, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := Start(ContextWithRemoteSpanContext(context.Background(), remote), "server", KindServer)
+	span.End()
+	if len(exporter.Spans()) != 0 {
+		t.Errorf("expect no span exported")
+	}
+
+	// nil span可以安全调用
+	var nilSpan *Span
+	nilSpan.SetAttribute("k", "v")
+	nilSpan.End()
+	if SpanFromContext(context.Background()) != nil {
+		t.Errorf("expect nil span")
+	}
+}